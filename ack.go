@@ -0,0 +1,137 @@
+package etl
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+type ackResolver func(ctx context.Context, err error) error
+
+// ackTracker resolves once every message attached to it has been acknowledged, or as soon as any of them has been
+// negatively acknowledged. It allows to acknowledge a message only after all messages derived from it were processed.
+type ackTracker struct {
+	sync.Mutex
+	pending  int
+	resolved bool
+	resolve  ackResolver
+}
+
+func newAckTracker(resolve ackResolver) *ackTracker {
+	return &ackTracker{resolve: resolve}
+}
+
+func (a *ackTracker) add() {
+	a.Lock()
+	a.pending++
+	a.Unlock()
+}
+
+func (a *ackTracker) release(ctx context.Context, err error) error {
+	a.Lock()
+	if a.resolved {
+		a.Unlock()
+		return nil
+	}
+
+	if err == nil {
+		a.pending--
+		if a.pending > 0 {
+			a.Unlock()
+			return nil
+		}
+	}
+
+	a.resolved = true
+	a.Unlock()
+
+	return a.resolve(ctx, err)
+}
+
+// ackHandle settles a single message attached to a tracker. It doesn't reference the message, so it can outlive it
+type ackHandle struct {
+	tracker *ackTracker
+	settled int32
+}
+
+func (h *ackHandle) resolve(ctx context.Context, err error) error {
+	if !atomic.CompareAndSwapInt32(&h.settled, 0, 1) {
+		return nil
+	}
+
+	return h.tracker.release(ctx, err)
+}
+
+// ackedMessage attaches acknowledgement tracking to an arbitrary message
+type ackedMessage struct {
+	Message
+	handle *ackHandle
+}
+
+func withAckTracker(msg Message, tracker *ackTracker) Message {
+	tracker.add()
+
+	return &ackedMessage{
+		Message: msg,
+		handle:  &ackHandle{tracker: tracker},
+	}
+}
+
+func (m *ackedMessage) Ack(ctx context.Context) error {
+	return m.handle.resolve(ctx, nil)
+}
+
+func (m *ackedMessage) Nack(ctx context.Context, err error) error {
+	if err == nil {
+		err = ErrMessageNacked
+	}
+
+	return m.handle.resolve(ctx, err)
+}
+
+// ackResolverOf returns a function acknowledging the message, without keeping the message itself if possible. Reports
+// false if acknowledging the message is a no-op.
+func ackResolverOf(msg Message) (ackResolver, bool) {
+	switch m := msg.(type) {
+	case *message:
+		return nil, false
+	case *ackedMessage:
+		return m.handle.resolve, true
+	case *headeredMessage:
+		return ackResolverOf(m.Message)
+	case *watermarkedMessage:
+		return ackResolverOf(m.Message)
+	}
+
+	return func(ctx context.Context, err error) error {
+		if err != nil {
+			return msg.Nack(ctx, err)
+		}
+
+		return msg.Ack(ctx)
+	}, true
+}
+
+func ackMessages(ctx context.Context, msgs []Message) error {
+	var err error
+	for _, msg := range msgs {
+		err = msg.Ack(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func nackMessages(ctx context.Context, msgs []Message, opErr error) error {
+	var err error
+	for _, msg := range msgs {
+		err = msg.Nack(ctx, opErr)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
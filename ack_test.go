@@ -0,0 +1,141 @@
+package etl_test
+
+import (
+	"context"
+	"errors"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+type fakeAckHook struct {
+	sync.Mutex
+	acked  []interface{}
+	nacked []interface{}
+	errs   []error
+}
+
+func (f *fakeAckHook) OnAck(ctx context.Context, msg etl.Message) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.acked = append(f.acked, msg.Payload())
+	return nil
+}
+
+func (f *fakeAckHook) OnNack(ctx context.Context, msg etl.Message, err error) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.nacked = append(f.nacked, msg.Payload())
+	f.errs = append(f.errs, err)
+	return nil
+}
+
+func TestAck_LoaderAcksExtractedMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hook := &fakeAckHook{}
+	extractor := etl.NewExtractor(newFakeExtractor(1, 2),
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	transformer := etl.NewTransformer(extractor.OutputCh(), fakeTransformer)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(transformer.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor, transformer, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{1, 2}, hook.acked)
+	require.Empty(t, hook.nacked)
+}
+
+func TestAck_LoaderNacksOnError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errTest := errors.New("test")
+	hook := &fakeAckHook{}
+	extractor := etl.NewExtractor(newFakeExtractor(1, 2),
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	loader := etl.NewLoaderBatched(extractor.OutputCh(), func(ctx context.Context, messages []etl.Message) error {
+		return errTest
+	}, etl.LoaderBatchedWithFailOnError(false), etl.LoaderBatchedWithFixedSizeBatches(2))
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.NoError(t, err)
+	require.Empty(t, hook.acked)
+	require.Equal(t, []interface{}{1, 2}, hook.nacked)
+	require.Equal(t, []error{errTest, errTest}, hook.errs)
+}
+
+func TestAck_DemuxAcksOnceAllDerivedMessagesAreAcked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errTest := errors.New("test")
+	hook := &fakeAckHook{}
+	extractor := etl.NewExtractor(newFakeExtractor(1, 2, 3),
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	transformer := etl.NewTransformerDemux(extractor.OutputCh(), func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+		// drop 3, forward 2 as is and fan out 1 into both outputs
+		switch inMsg.Payload().(int) {
+		case 1:
+			err := sender.SendCh(ctx, 0, 1)
+			if err != nil {
+				return err
+			}
+
+			return sender.SendCh(ctx, 1, 1)
+		case 2:
+			return sender.SendChMessage(ctx, 1, inMsg)
+		}
+
+		return nil
+	}, 2)
+	first := &fakeLoader{}
+	loader1 := etl.NewLoader(transformer.OutputCh(0), first.Handle)
+	loader2 := etl.NewLoader(transformer.OutputCh(1), func(ctx context.Context, message etl.Message) error {
+		if message.Payload() == 2 {
+			return errTest
+		}
+
+		return nil
+	}, etl.LoaderWithFailOnError(false))
+
+	err := etl.RunAll(ctx, extractor, transformer, loader1, loader2)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []interface{}{1, 3}, hook.acked)
+	require.Equal(t, []interface{}{2}, hook.nacked)
+	require.Equal(t, []error{errTest}, hook.errs)
+}
+
+func TestAck_MessageIsSettledOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hook := &fakeAckHook{}
+	extractor := etl.NewExtractor(newFakeExtractor(1),
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	loader := etl.NewLoader(extractor.OutputCh(), func(ctx context.Context, message etl.Message) error {
+		err := message.Ack(ctx)
+		if err != nil {
+			return err
+		}
+
+		return message.Nack(ctx, nil)
+	})
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{1}, hook.acked)
+	require.Empty(t, hook.nacked)
+}
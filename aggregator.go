@@ -0,0 +1,331 @@
+package etl
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"io"
+	"sort"
+)
+
+// Aggregate is a payload of messages sent by Aggregator
+type Aggregate struct {
+	Key   string
+	Value interface{}
+}
+
+// Aggregator groups messages of a finite stream by key and folds them with a reducer. Once input channel is closed,
+// a message with Aggregate payload is sent for every key, in order of keys. Aggregates exceeding the memory budget
+// are spilled to temporary files, and merged at the end. Input messages are acknowledged once the aggregate of their key
+// is acknowledged. Only their acknowledgement is kept in memory until then, without payloads.
+type Aggregator interface {
+	Runner
+	OutputCh() <-chan Message
+}
+
+type aggregator struct {
+	keyFn   PartitionKeyFunc
+	reducer Reducer
+
+	inputCh  <-chan Message
+	outputCh chan Message
+	state    map[string]interface{}
+	acks     map[string][]ackResolver
+	spills   []string
+
+	opts *aggregatorOptions
+}
+
+func NewAggregator(inputCh <-chan Message, keyFn PartitionKeyFunc, reducer Reducer, optsSetters ...AggregatorOption) Aggregator {
+	opts := newAggregatorOptions(optsSetters...)
+
+	return &aggregator{
+		keyFn:   keyFn,
+		reducer: reducer,
+
+		inputCh:  inputCh,
+		outputCh: make(chan Message, opts.outputChannelBufferSize),
+		state:    make(map[string]interface{}),
+		acks:     make(map[string][]ackResolver),
+
+		opts: opts,
+	}
+}
+
+func (a *aggregator) OutputCh() <-chan Message {
+	return a.outputCh
+}
+
+func (a *aggregator) preRunHooks(ctx context.Context) error {
+	var err error
+	for _, hook := range a.opts.hooksPreRun {
+		err = hook(ctx, a.inputCh)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *aggregator) onErrorHook(ctx context.Context, inMsg Message, opErr error) error {
+	var err error
+	for _, hook := range a.opts.hooksOnError {
+		err = hook(ctx, inMsg, opErr)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *aggregator) onSpillHook(ctx context.Context, keys int, path string) error {
+	var err error
+	for _, hook := range a.opts.hooksOnSpill {
+		err = hook(ctx, keys, path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run aggregates messages, until input channel is closed, and sends aggregates. Note that execution of this function is blocking, until processing is finished.
+func (a *aggregator) Run(ctx context.Context) error {
+	err := a.preRunHooks(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to run aggregator preRunHooks")
+	}
+
+	defer close(a.outputCh)
+	defer func() {
+		removeSpills(a.spills)
+	}()
+
+	var (
+		inMsg Message
+		ok    bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case inMsg, ok = <-a.inputCh:
+			if !ok {
+				return a.emit(ctx)
+			}
+
+			err = a.add(ctx, inMsg)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// add folds message into the aggregate of its key. Returns an error only if processing should be stopped.
+func (a *aggregator) add(ctx context.Context, inMsg Message) error {
+	key := a.keyFn(inMsg)
+
+	acc, ok := a.state[key]
+	if !ok {
+		acc = a.reducer.Init()
+	}
+
+	opErr := callRecovering(func() error {
+		var err error
+		acc, err = a.reducer.Add(acc, inMsg)
+		return err
+	}, inMsg)
+	if opErr != nil {
+		err := a.onErrorHook(ctx, inMsg, opErr)
+		if err != nil {
+			return errors.Wrap(err, "running aggregator on error hook has failed")
+		}
+
+		err = inMsg.Nack(ctx, opErr)
+		if err != nil {
+			return errors.Wrap(err, "failed to nack aggregator message")
+		}
+
+		if a.opts.failOnErr {
+			return opErr
+		}
+
+		return nil
+	}
+	a.state[key] = acc
+
+	resolve, tracked := ackResolverOf(inMsg)
+	if tracked {
+		a.acks[key] = append(a.acks[key], resolve)
+	}
+
+	if a.opts.maxKeysInMemory > 0 && len(a.state) > a.opts.maxKeysInMemory {
+		return a.spill(ctx)
+	}
+
+	return nil
+}
+
+func (a *aggregator) sortedKeys() []string {
+	keys := make([]string, 0, len(a.state))
+	for key := range a.state {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// spill writes aggregates sorted by key to a temporary file, and clears the state
+func (a *aggregator) spill(ctx context.Context) error {
+	if a.reducer.Merge == nil {
+		return ErrReducerMergeMissing
+	}
+
+	w, err := newSpillWriter(a.opts.tempDir, "etl-aggregator-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create aggregator spill file")
+	}
+
+	var value []byte
+	for _, key := range a.sortedKeys() {
+		value, err = a.opts.codec.Encode(a.state[key])
+		if err != nil {
+			_, _ = w.close()
+			return errors.Wrapf(err, "failed to encode aggregate of key %q", key)
+		}
+
+		err = w.write([]byte(key), value)
+		if err != nil {
+			_, _ = w.close()
+			return errors.Wrap(err, "failed to write aggregator spill file")
+		}
+	}
+
+	path, err := w.close()
+	a.spills = append(a.spills, path)
+	if err != nil {
+		return errors.Wrap(err, "failed to write aggregator spill file")
+	}
+
+	err = a.onSpillHook(ctx, len(a.state), path)
+	if err != nil {
+		return errors.Wrap(err, "failed to run aggregator onSpill hook")
+	}
+
+	a.state = make(map[string]interface{})
+
+	return nil
+}
+
+// emit merges spilled aggregates with the ones in memory, and sends them in order of keys
+func (a *aggregator) emit(ctx context.Context) error {
+	var (
+		sources = make([]mergeSource, 0, len(a.spills)+1)
+		keys    = a.sortedKeys()
+	)
+	for _, path := range a.spills {
+		r, err := newSpillReader(path)
+		if err != nil {
+			return errors.Wrap(err, "failed to open aggregator spill file")
+		}
+		defer r.close()
+
+		sources = append(sources, a.spillSource(r))
+	}
+	sources = append(sources, func() (interface{}, bool, error) {
+		if len(keys) == 0 {
+			return nil, false, nil
+		}
+
+		agg := Aggregate{Key: keys[0], Value: a.state[keys[0]]}
+		keys = keys[1:]
+
+		return agg, true, nil
+	})
+
+	var current *Aggregate
+	err := mergeSorted(sources, func(x, y interface{}) bool {
+		return x.(Aggregate).Key < y.(Aggregate).Key
+	}, func(v interface{}) error {
+		var (
+			agg = v.(Aggregate)
+			err error
+		)
+		if current != nil && current.Key == agg.Key {
+			current.Value, err = a.reducer.Merge(current.Value, agg.Value)
+			if err != nil {
+				return errors.Wrapf(err, "failed to merge aggregates of key %q", agg.Key)
+			}
+
+			return nil
+		}
+
+		if current != nil {
+			err = a.send(ctx, *current)
+			if err != nil {
+				return err
+			}
+		}
+		current = &agg
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if current != nil {
+		return a.send(ctx, *current)
+	}
+
+	return nil
+}
+
+func (a *aggregator) spillSource(r *spillReader) mergeSource {
+	return func() (interface{}, bool, error) {
+		fields, err := r.read(2)
+		if err == io.EOF {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, errors.Wrap(err, "failed to read aggregator spill file")
+		}
+
+		value, err := a.opts.codec.Decode(fields[1])
+		if err != nil {
+			return nil, false, errors.Wrap(err, "failed to decode spilled aggregate")
+		}
+
+		return Aggregate{Key: string(fields[0]), Value: value}, true, nil
+	}
+}
+
+// send sends the aggregate, acknowledging input messages of its key once it's acknowledged
+func (a *aggregator) send(ctx context.Context, agg Aggregate) error {
+	acks := a.acks[agg.Key]
+	delete(a.acks, agg.Key)
+
+	tracker := newAckTracker(func(ctx context.Context, err error) error {
+		var resolveErr error
+		for _, resolve := range acks {
+			resolveErr = resolve(ctx, err)
+			if resolveErr != nil {
+				return resolveErr
+			}
+		}
+
+		return nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case a.outputCh <- withAckTracker(NewMessage(agg), tracker):
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package etl
+
+import (
+	"context"
+	"os"
+)
+
+type aggregatorOptions struct {
+	hooksPreRun  []AggregatorPreRunHook
+	hooksOnError []AggregatorOnErrorHook
+	hooksOnSpill []AggregatorOnSpillHook
+
+	codec                   Codec
+	tempDir                 string
+	maxKeysInMemory         int
+	outputChannelBufferSize int
+	failOnErr               bool
+}
+
+func newAggregatorOptions(optsSetters ...AggregatorOption) *aggregatorOptions {
+	opts := &aggregatorOptions{
+		codec:     GobCodec(),
+		tempDir:   os.TempDir(),
+		failOnErr: true,
+	}
+
+	for _, setter := range optsSetters {
+		if setter != nil {
+			setter(opts)
+		}
+	}
+
+	return opts
+}
+
+type AggregatorOption func(o *aggregatorOptions)
+
+type AggregatorPreRunHook func(ctx context.Context, inputCh <-chan Message) error
+
+func AggregatorWithPreRunHook(hook AggregatorPreRunHook) AggregatorOption {
+	return func(o *aggregatorOptions) { o.hooksPreRun = append(o.hooksPreRun, hook) }
+}
+
+// AggregatorOnErrorHook is called when reducer fails to add a message to the aggregate
+type AggregatorOnErrorHook func(ctx context.Context, inMsg Message, err error) error
+
+func AggregatorWithOnErrorHook(hook AggregatorOnErrorHook) AggregatorOption {
+	return func(o *aggregatorOptions) { o.hooksOnError = append(o.hooksOnError, hook) }
+}
+
+// AggregatorOnSpillHook is called once aggregates of keys have been spilled to a file
+type AggregatorOnSpillHook func(ctx context.Context, keys int, path string) error
+
+func AggregatorWithOnSpillHook(hook AggregatorOnSpillHook) AggregatorOption {
+	return func(o *aggregatorOptions) { o.hooksOnSpill = append(o.hooksOnSpill, hook) }
+}
+
+func AggregatorWithOutputChannelBufferSize(size int) AggregatorOption {
+	return func(o *aggregatorOptions) { o.outputChannelBufferSize = size }
+}
+
+func AggregatorWithFailOnError(failOnErr bool) AggregatorOption {
+	return func(o *aggregatorOptions) { o.failOnErr = failOnErr }
+}
+
+// AggregatorWithMaxKeysInMemory sets memory budget of the aggregator, as a number of keys. Once it's exceeded,
+// aggregates are spilled to a temporary file. By default, all aggregates are kept in memory
+func AggregatorWithMaxKeysInMemory(maxKeys int) AggregatorOption {
+	return func(o *aggregatorOptions) { o.maxKeysInMemory = maxKeys }
+}
+
+// AggregatorWithCodec sets codec of spilled aggregates. GobCodec is used by default
+func AggregatorWithCodec(codec Codec) AggregatorOption {
+	return func(o *aggregatorOptions) { o.codec = codec }
+}
+
+// AggregatorWithTempDir sets directory of spill files. By default, os.TempDir is used
+func AggregatorWithTempDir(dir string) AggregatorOption {
+	return func(o *aggregatorOptions) { o.tempDir = dir }
+}
@@ -0,0 +1,135 @@
+package etl_test
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func fakeEventAt(msg etl.Message) float64 {
+	return float64(msg.Payload().(fakeEvent).at)
+}
+
+func newFakeEventsChannel() <-chan etl.Message {
+	return newFilledChannel(
+		fakeEvent{user: "b", at: 1},
+		fakeEvent{user: "a", at: 2},
+		fakeEvent{user: "c", at: 3},
+		fakeEvent{user: "a", at: 4},
+		fakeEvent{user: "b", at: 5},
+		fakeEvent{user: "d", at: 6},
+		fakeEvent{user: "a", at: 7},
+	)
+}
+
+func TestAggregator_Reducers(t *testing.T) {
+	for name, tt := range map[string]struct {
+		reducer etl.Reducer
+		want    []interface{}
+	}{
+		"count": {
+			reducer: etl.CountReducer(),
+			want:    []interface{}{3, 2, 1, 1},
+		},
+		"sum": {
+			reducer: etl.SumReducer(fakeEventAt),
+			want:    []interface{}{13.0, 6.0, 3.0, 6.0},
+		},
+		"min": {
+			reducer: etl.MinReducer(fakeEventAt),
+			want:    []interface{}{2.0, 1.0, 3.0, 6.0},
+		},
+		"max": {
+			reducer: etl.MaxReducer(fakeEventAt),
+			want:    []interface{}{7.0, 5.0, 3.0, 6.0},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, maxKeys := range []int{0, 1, 2} {
+				dir := t.TempDir()
+				var spills int
+				aggregator := etl.NewAggregator(newFakeEventsChannel(), fakeEventUser, tt.reducer,
+					etl.AggregatorWithMaxKeysInMemory(maxKeys),
+					etl.AggregatorWithTempDir(dir),
+					etl.AggregatorWithOnSpillHook(func(ctx context.Context, keys int, path string) error {
+						spills++
+						return nil
+					}),
+				)
+
+				var want []interface{}
+				for i, key := range []string{"a", "b", "c", "d"} {
+					want = append(want, etl.Aggregate{Key: key, Value: tt.want[i]})
+				}
+				require.Equal(t, want, runIntoLoader(t, aggregator, aggregator.OutputCh()), "max keys %d", maxKeys)
+				require.Equal(t, maxKeys > 0, spills > 0, "max keys %d", maxKeys)
+
+				files, err := os.ReadDir(dir)
+				require.NoError(t, err)
+				require.Empty(t, files, "expected spill files to be removed")
+			}
+		})
+	}
+}
+
+func TestAggregator_SpillRequiresMerge(t *testing.T) {
+	reducer := etl.CountReducer()
+	reducer.Merge = nil
+	aggregator := etl.NewAggregator(newFakeEventsChannel(), fakeEventUser, reducer,
+		etl.AggregatorWithMaxKeysInMemory(1),
+		etl.AggregatorWithTempDir(t.TempDir()),
+	)
+
+	err := aggregator.Run(context.Background())
+	require.Equal(t, etl.ErrReducerMergeMissing, errors.Cause(err))
+}
+
+func TestAggregator_AcksInputsOnceAggregateIsAcked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errTest := errors.New("test")
+	hook := &fakeAckHook{}
+	extractor := etl.NewExtractor(newFakeExtractor(
+		fakeEvent{user: "b", at: 1},
+		fakeEvent{user: "a", at: 2},
+		fakeEvent{user: "b", at: 3},
+		fakeEvent{user: "a", at: 4},
+	),
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	aggregator := etl.NewAggregator(extractor.OutputCh(), fakeEventUser, etl.CountReducer(),
+		etl.AggregatorWithMaxKeysInMemory(1),
+		etl.AggregatorWithTempDir(t.TempDir()),
+	)
+
+	var (
+		loaded      []interface{}
+		ackedBefore int
+	)
+	loader := etl.NewLoader(aggregator.OutputCh(), func(ctx context.Context, msg etl.Message) error {
+		hook.Lock()
+		ackedBefore += len(hook.acked)
+		hook.Unlock()
+
+		loaded = append(loaded, msg.Payload())
+
+		if msg.Payload().(etl.Aggregate).Key == "a" {
+			return errTest
+		}
+
+		return nil
+	}, etl.LoaderWithFailOnError(false))
+
+	err := etl.RunAll(ctx, extractor, aggregator, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{etl.Aggregate{Key: "a", Value: 2}, etl.Aggregate{Key: "b", Value: 2}}, loaded)
+	// inputs of spilled aggregates are not acknowledged until their aggregates are loaded
+	require.Zero(t, ackedBefore)
+	require.Equal(t, []interface{}{fakeEvent{user: "a", at: 2}, fakeEvent{user: "a", at: 4}}, hook.nacked)
+	require.Equal(t, []interface{}{fakeEvent{user: "b", at: 1}, fakeEvent{user: "b", at: 3}}, hook.acked)
+}
@@ -0,0 +1,194 @@
+package etl
+
+import (
+	"context"
+	"time"
+)
+
+// LoaderBatcher collects a batch of messages from the input channel. It returns an empty batch once the input channel
+// is closed.
+type LoaderBatcher func(ctx context.Context, inMsgCh <-chan Message) ([]Message, error)
+
+// FixedSizeBatcher collects batches of maxItems messages. The last batch might be smaller, if input channel is closed
+func FixedSizeBatcher(maxItems int) LoaderBatcher {
+	return func(ctx context.Context, inMsgCh <-chan Message) ([]Message, error) {
+		var (
+			messages = make([]Message, 0, maxItems)
+			inMsg    Message
+			ok       bool
+		)
+		for {
+			if len(messages) >= maxItems {
+				return messages, nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case inMsg, ok = <-inMsgCh:
+				if !ok {
+					return messages, nil
+				}
+
+				messages = append(messages, inMsg)
+			}
+		}
+	}
+}
+
+// DrainedChannelBatcher waits for a message and collects messages already available in input channel, up to maxItems
+func DrainedChannelBatcher(maxItems int) LoaderBatcher {
+	return func(ctx context.Context, inMsgCh <-chan Message) ([]Message, error) {
+		var (
+			messages = make([]Message, 0, maxItems)
+			inMsg    Message
+			ok       bool
+		)
+		for {
+			if len(messages) >= maxItems {
+				return messages, nil
+			}
+
+			if len(messages) == 0 {
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case inMsg, ok = <-inMsgCh:
+					if !ok {
+						return messages, nil
+					}
+
+					messages = append(messages, inMsg)
+				}
+
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case inMsg, ok = <-inMsgCh:
+				if !ok {
+					return messages, nil
+				}
+
+				messages = append(messages, inMsg)
+			default:
+				return messages, nil
+			}
+		}
+	}
+}
+
+// ThrottledBatcher collects messages for an interval since the first message of a batch, up to maxItems
+func ThrottledBatcher(interval time.Duration, maxItems int) LoaderBatcher {
+	return func(ctx context.Context, inMsgCh <-chan Message) ([]Message, error) {
+		var (
+			messages = make([]Message, 0, maxItems)
+			inMsg    Message
+			ticker   *time.Timer
+			ok       bool
+		)
+		for {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case inMsg, ok = <-inMsgCh:
+				if !ok {
+					return nil, nil
+				}
+
+				messages = append(messages, inMsg)
+
+				ticker = time.NewTimer(interval)
+
+				for {
+					if len(messages) >= maxItems {
+						return messages, nil
+					}
+
+					select {
+					case <-ctx.Done():
+						return nil, ctx.Err()
+					case inMsg, ok = <-inMsgCh:
+						if !ok {
+							// channel closed. There's not going to be any new messages. But return what we have buffered
+							return messages, nil
+						}
+
+						messages = append(messages, inMsg)
+					case <-ticker.C:
+						return messages, nil
+					}
+				}
+			}
+		}
+	}
+}
+
+// DebouncedBatcher collects messages until there's no new message for an interval, up to maxItems
+func DebouncedBatcher(interval time.Duration, maxItems int) LoaderBatcher {
+	return func(ctx context.Context, inMsgCh <-chan Message) ([]Message, error) {
+		var (
+			messages = make([]Message, 0, maxItems)
+			inMsg    Message
+			ticker   *time.Timer
+			ok       bool
+		)
+		for {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case inMsg, ok = <-inMsgCh:
+				if !ok {
+					return nil, nil
+				}
+
+				messages = append(messages, inMsg)
+
+				ticker = time.NewTimer(interval)
+
+				for {
+					if len(messages) >= maxItems {
+						return messages, nil
+					}
+
+					ticker.Reset(interval)
+
+					select {
+					case <-ctx.Done():
+						return nil, ctx.Err()
+					case inMsg, ok = <-inMsgCh:
+						if !ok {
+							// channel closed. There's not going to be any new messages. But return what we have buffered
+							return messages, nil
+						}
+
+						messages = append(messages, inMsg)
+					case <-ticker.C:
+						return messages, nil
+					}
+				}
+			}
+		}
+	}
+}
+
+func defaultLoaderBatcher(ctx context.Context, inMsgCh <-chan Message) ([]Message, error) {
+	var (
+		inMsg Message
+		ok    bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case inMsg, ok = <-inMsgCh:
+			if !ok {
+				return nil, nil
+			}
+
+			return []Message{inMsg}, nil
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package etl
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// Broadcast delivers every message from the input channel to all output channels. Messages sent to output channels
+// share acknowledgement, so input message is acknowledged once all outputs acknowledge it.
+type Broadcast interface {
+	Runner
+	OutputCh(i int) <-chan Message
+}
+
+type broadcast struct {
+	inputCh   <-chan Message
+	outputChs []chan Message
+	detached  []bool
+
+	opts *broadcastOptions
+}
+
+func NewBroadcast(inputCh <-chan Message, outputChannelsNr uint, optsSetters ...BroadcastOption) Broadcast {
+	opts := newBroadcastOptions(optsSetters...)
+
+	var outputChs = make([]chan Message, outputChannelsNr)
+	for i := uint(0); i < outputChannelsNr; i++ {
+		outputChs[i] = make(chan Message, opts.bufferSize(i))
+	}
+
+	return &broadcast{
+		inputCh:   inputCh,
+		outputChs: outputChs,
+		detached:  make([]bool, outputChannelsNr),
+
+		opts: opts,
+	}
+}
+
+func (b *broadcast) OutputCh(i int) <-chan Message {
+	if i >= len(b.outputChs) || i < 0 {
+		panic(ErrOutputMessageOutOfChannelsRange)
+	}
+
+	return b.outputChs[i]
+}
+
+func (b *broadcast) preRunHooks(ctx context.Context) error {
+	var err error
+	for _, hook := range b.opts.hooksPreRun {
+		err = hook(ctx, b.inputCh)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *broadcast) onSendHook(ctx context.Context, msg Message, outChNr uint) error {
+	var err error
+	for _, hook := range b.opts.hooksOnSend {
+		err = hook(ctx, msg, outChNr)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *broadcast) onDropHook(ctx context.Context, msg Message, outChNr uint) error {
+	var err error
+	for _, hook := range b.opts.hooksOnDrop {
+		err = hook(ctx, msg, outChNr)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *broadcast) onDetachHook(ctx context.Context, outChNr uint) error {
+	var err error
+	for _, hook := range b.opts.hooksOnDetach {
+		err = hook(ctx, outChNr)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *broadcast) closeChannels() {
+	for i, ch := range b.outputChs {
+		if !b.detached[i] {
+			close(ch)
+		}
+	}
+}
+
+// Run broadcasts messages, until input channel is closed. Note that execution of this function is blocking, until processing is finished.
+func (b *broadcast) Run(ctx context.Context) error {
+	err := b.preRunHooks(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to run broadcast preRunHooks")
+	}
+
+	defer b.closeChannels()
+
+	var (
+		inMsg Message
+		ok    bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case inMsg, ok = <-b.inputCh:
+			if !ok {
+				return nil
+			}
+
+			err = b.broadcast(ctx, inMsg)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (b *broadcast) broadcast(ctx context.Context, inMsg Message) error {
+	tracker := newAckTracker(func(ctx context.Context, err error) error {
+		if err != nil {
+			return inMsg.Nack(ctx, err)
+		}
+
+		return inMsg.Ack(ctx)
+	})
+	tracker.add()
+
+	var (
+		outMsg    Message
+		delivered bool
+		err       error
+	)
+	for i := range b.outputChs {
+		if b.detached[i] {
+			continue
+		}
+
+		outMsg = withAckTracker(inMsg, tracker)
+
+		delivered, err = b.send(ctx, uint(i), outMsg)
+		if err != nil {
+			return err
+		}
+
+		if delivered {
+			err = b.onSendHook(ctx, inMsg, uint(i))
+			if err != nil {
+				return errors.Wrap(err, "failed to run broadcast onSend hook")
+			}
+
+			continue
+		}
+
+		// message is not going to be processed by the slow consumer, so don't wait for its acknowledgement
+		err = outMsg.Ack(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to ack dropped broadcast message")
+		}
+
+		err = b.onDropHook(ctx, inMsg, uint(i))
+		if err != nil {
+			return errors.Wrap(err, "failed to run broadcast onDrop hook")
+		}
+
+		if b.opts.slowConsumerPolicy == BroadcastDetach {
+			b.detached[i] = true
+			close(b.outputChs[i])
+
+			err = b.onDetachHook(ctx, uint(i))
+			if err != nil {
+				return errors.Wrap(err, "failed to run broadcast onDetach hook")
+			}
+		}
+	}
+
+	err = tracker.release(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to ack broadcast input message")
+	}
+
+	return nil
+}
+
+// send sends message to the output channel according to slow consumer policy. Returns false if message hasn't been
+// accepted within the timeout.
+func (b *broadcast) send(ctx context.Context, outChNr uint, msg Message) (bool, error) {
+	if b.opts.slowConsumerPolicy == BroadcastBlock {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case b.outputChs[outChNr] <- msg:
+			return true, nil
+		}
+	}
+
+	if b.opts.slowConsumerTimeout <= 0 {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case b.outputChs[outChNr] <- msg:
+			return true, nil
+		default:
+			return false, nil
+		}
+	}
+
+	timer := time.NewTimer(b.opts.slowConsumerTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case b.outputChs[outChNr] <- msg:
+		return true, nil
+	case <-timer.C:
+		return false, nil
+	}
+}
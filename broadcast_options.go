@@ -0,0 +1,103 @@
+package etl
+
+import (
+	"context"
+	"time"
+)
+
+// BroadcastSlowConsumerPolicy decides what happens when an output channel can't accept a message
+type BroadcastSlowConsumerPolicy int
+
+const (
+	// BroadcastBlock waits until output channel accepts the message
+	BroadcastBlock BroadcastSlowConsumerPolicy = iota
+	// BroadcastDrop skips the message for an output channel that didn't accept it within the timeout
+	BroadcastDrop
+	// BroadcastDetach closes an output channel that didn't accept a message within the timeout, and stops sending to it
+	BroadcastDetach
+)
+
+type broadcastOptions struct {
+	hooksPreRun   []BroadcastPreRunHook
+	hooksOnSend   []BroadcastOnSendHook
+	hooksOnDrop   []BroadcastOnDropHook
+	hooksOnDetach []BroadcastOnDetachHook
+
+	slowConsumerPolicy  BroadcastSlowConsumerPolicy
+	slowConsumerTimeout time.Duration
+
+	outputChannelBufferSize  int
+	outputChannelBufferSizes map[uint]int
+}
+
+func newBroadcastOptions(optsSetters ...BroadcastOption) *broadcastOptions {
+	opts := &broadcastOptions{
+		slowConsumerPolicy:       BroadcastBlock,
+		outputChannelBufferSizes: make(map[uint]int),
+	}
+
+	for _, setter := range optsSetters {
+		if setter != nil {
+			setter(opts)
+		}
+	}
+
+	return opts
+}
+
+func (o *broadcastOptions) bufferSize(outChNr uint) int {
+	size, ok := o.outputChannelBufferSizes[outChNr]
+	if !ok {
+		return o.outputChannelBufferSize
+	}
+
+	return size
+}
+
+type BroadcastOption func(o *broadcastOptions)
+
+type BroadcastPreRunHook func(ctx context.Context, inputCh <-chan Message) error
+
+func BroadcastWithPreRunHook(hook BroadcastPreRunHook) BroadcastOption {
+	return func(o *broadcastOptions) { o.hooksPreRun = append(o.hooksPreRun, hook) }
+}
+
+// BroadcastOnSendHook is called after message has been sent to an output channel
+type BroadcastOnSendHook func(ctx context.Context, msg Message, outChNr uint) error
+
+func BroadcastWithOnSendHook(hook BroadcastOnSendHook) BroadcastOption {
+	return func(o *broadcastOptions) { o.hooksOnSend = append(o.hooksOnSend, hook) }
+}
+
+// BroadcastOnDropHook is called when message has been dropped for an output channel, or the output has been detached
+type BroadcastOnDropHook func(ctx context.Context, msg Message, outChNr uint) error
+
+func BroadcastWithOnDropHook(hook BroadcastOnDropHook) BroadcastOption {
+	return func(o *broadcastOptions) { o.hooksOnDrop = append(o.hooksOnDrop, hook) }
+}
+
+// BroadcastOnDetachHook is called when an output channel has been detached
+type BroadcastOnDetachHook func(ctx context.Context, outChNr uint) error
+
+func BroadcastWithOnDetachHook(hook BroadcastOnDetachHook) BroadcastOption {
+	return func(o *broadcastOptions) { o.hooksOnDetach = append(o.hooksOnDetach, hook) }
+}
+
+// BroadcastWithSlowConsumerPolicy sets what happens when an output channel doesn't accept a message within the
+// timeout. Timeout is ignored by BroadcastBlock policy.
+func BroadcastWithSlowConsumerPolicy(policy BroadcastSlowConsumerPolicy, timeout time.Duration) BroadcastOption {
+	return func(o *broadcastOptions) {
+		o.slowConsumerPolicy = policy
+		o.slowConsumerTimeout = timeout
+	}
+}
+
+// BroadcastWithOutputChannelBufferSize sets buffer size of all output channels
+func BroadcastWithOutputChannelBufferSize(size int) BroadcastOption {
+	return func(o *broadcastOptions) { o.outputChannelBufferSize = size }
+}
+
+// BroadcastWithOutputChannelBufferSizeFor sets buffer size of the specified output channel
+func BroadcastWithOutputChannelBufferSizeFor(outChNr uint, size int) BroadcastOption {
+	return func(o *broadcastOptions) { o.outputChannelBufferSizes[outChNr] = size }
+}
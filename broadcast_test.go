@@ -0,0 +1,172 @@
+package etl_test
+
+import (
+	"context"
+	"errors"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeBroadcastHook struct {
+	sync.Mutex
+	sent     map[uint][]interface{}
+	dropped  map[uint][]interface{}
+	detached []uint
+}
+
+func newFakeBroadcastHook() *fakeBroadcastHook {
+	return &fakeBroadcastHook{
+		sent:    make(map[uint][]interface{}),
+		dropped: make(map[uint][]interface{}),
+	}
+}
+
+func (f *fakeBroadcastHook) OnSend(ctx context.Context, msg etl.Message, outChNr uint) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.sent[outChNr] = append(f.sent[outChNr], msg.Payload())
+	return nil
+}
+
+func (f *fakeBroadcastHook) OnDrop(ctx context.Context, msg etl.Message, outChNr uint) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.dropped[outChNr] = append(f.dropped[outChNr], msg.Payload())
+	return nil
+}
+
+func (f *fakeBroadcastHook) OnDetach(ctx context.Context, outChNr uint) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.detached = append(f.detached, outChNr)
+	return nil
+}
+
+func TestBroadcast_DeliversToAllOutputs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hook := newFakeBroadcastHook()
+	broadcast := etl.NewBroadcast(newFilledChannel(1, 2, 3), 2, etl.BroadcastWithOnSendHook(hook.OnSend))
+	l1 := &fakeLoader{}
+	loader1 := etl.NewLoader(broadcast.OutputCh(0), l1.Handle)
+	l2 := &fakeLoader{}
+	loader2 := etl.NewLoader(broadcast.OutputCh(1), l2.Handle)
+
+	err := etl.RunAll(ctx, broadcast, loader1, loader2)
+	require.NoError(t, err)
+
+	for _, l := range []*fakeLoader{l1, l2} {
+		var payload []interface{}
+		for _, call := range l.calls {
+			payload = append(payload, call.Payload())
+		}
+		require.Equal(t, []interface{}{1, 2, 3}, payload)
+	}
+	require.Equal(t, []interface{}{1, 2, 3}, hook.sent[0])
+	require.Equal(t, []interface{}{1, 2, 3}, hook.sent[1])
+}
+
+func TestBroadcast_AcksOnceAllOutputsAck(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hook := &fakeAckHook{}
+	extractor := etl.NewExtractor(newFakeExtractor(1, 2),
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	errTest := errors.New("test")
+	broadcast := etl.NewBroadcast(extractor.OutputCh(), 2)
+	l := &fakeLoader{}
+	loader1 := etl.NewLoader(broadcast.OutputCh(0), l.Handle)
+	loader2 := etl.NewLoader(broadcast.OutputCh(1), func(ctx context.Context, msg etl.Message) error {
+		if msg.Payload() == 2 {
+			return errTest
+		}
+
+		return nil
+	}, etl.LoaderWithFailOnError(false))
+
+	err := etl.RunAll(ctx, extractor, broadcast, loader1, loader2)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{1}, hook.acked)
+	require.Equal(t, []interface{}{2}, hook.nacked)
+}
+
+func TestBroadcast_DropsForSlowConsumer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hook := newFakeBroadcastHook()
+	broadcast := etl.NewBroadcast(newFilledChannel(1, 2, 3), 2,
+		etl.BroadcastWithOutputChannelBufferSizeFor(0, 3),
+		etl.BroadcastWithSlowConsumerPolicy(etl.BroadcastDrop, 0),
+		etl.BroadcastWithOnDropHook(hook.OnDrop),
+	)
+
+	err := broadcast.Run(ctx)
+	require.NoError(t, err)
+
+	var received []interface{}
+	for msg := range broadcast.OutputCh(0) {
+		received = append(received, msg.Payload())
+	}
+	require.Equal(t, []interface{}{1, 2, 3}, received)
+
+	_, ok := <-broadcast.OutputCh(1)
+	require.False(t, ok)
+	require.Equal(t, []interface{}{1, 2, 3}, hook.dropped[1])
+}
+
+func TestBroadcast_DetachesSlowConsumer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hook := newFakeBroadcastHook()
+	broadcast := etl.NewBroadcast(newFilledChannel(1, 2, 3), 2,
+		etl.BroadcastWithOutputChannelBufferSize(1),
+		etl.BroadcastWithOutputChannelBufferSizeFor(0, 3),
+		etl.BroadcastWithSlowConsumerPolicy(etl.BroadcastDetach, 10*time.Millisecond),
+		etl.BroadcastWithOnDropHook(hook.OnDrop),
+		etl.BroadcastWithOnDetachHook(hook.OnDetach),
+	)
+
+	err := broadcast.Run(ctx)
+	require.NoError(t, err)
+
+	var received []interface{}
+	for msg := range broadcast.OutputCh(1) {
+		received = append(received, msg.Payload())
+	}
+	require.Equal(t, []interface{}{1}, received)
+	require.Equal(t, []interface{}{2}, hook.dropped[1])
+	require.Equal(t, []uint{1}, hook.detached)
+	require.Len(t, broadcast.OutputCh(0), 3)
+}
+
+func TestPipeline_Broadcast(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l1 := &fakeLoader{}
+	l2 := &fakeLoader{}
+	p := etl.NewPipeline().
+		AddExtractor("src", newFakeExtractor(1, 2)).
+		AddBroadcast("tee", 2).
+		AddLoader("sink1", l1.Handle).
+		AddLoader("sink2", l2.Handle)
+	p.From("src").To("tee").To("sink1")
+	p.FromCh("tee", 1).To("sink2")
+
+	err := p.Run(ctx)
+	require.NoError(t, err)
+	require.Len(t, l1.calls, 2)
+	require.Len(t, l2.calls, 2)
+}
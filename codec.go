@@ -0,0 +1,45 @@
+package etl
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// Codec encodes values, e.g. so stages can spill them to disk
+type Codec interface {
+	Encode(v interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+type gobCodec struct{}
+
+type gobValue struct {
+	Value interface{}
+}
+
+// GobCodec encodes values with encoding/gob. Types other than basic ones must be registered with gob.Register
+func GobCodec() Codec {
+	return gobCodec{}
+}
+
+func (gobCodec) Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := gob.NewEncoder(&buf).Encode(gobValue{Value: v})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Decode(data []byte) (interface{}, error) {
+	var v gobValue
+
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.Value, nil
+}
@@ -0,0 +1,80 @@
+package etl
+
+import (
+	"context"
+	"time"
+)
+
+// DeadLetter describes a message that stage failed to process. Messages from failed batches are reported separately.
+type DeadLetter struct {
+	Message  Message
+	Err      error
+	Stage    string
+	Attempts int
+	FailedAt time.Time
+}
+
+// DeadLetterSink receives messages that exhausted error handling of a stage. Dead-lettered messages are acknowledged,
+// as they can be replayed from the sink.
+type DeadLetterSink func(ctx context.Context, deadLetter DeadLetter) error
+
+func newDeadLetter(ctx context.Context, stage string, msg Message, err error) DeadLetter {
+	return DeadLetter{
+		Message:  msg,
+		Err:      err,
+		Stage:    stage,
+		Attempts: AttemptFromContext(ctx),
+		FailedAt: time.Now(),
+	}
+}
+
+func newDeadLetterMessage(deadLetter DeadLetter) Message {
+	return NewMessage(
+		deadLetter,
+		MessageWithProcessingStartedAt(deadLetter.Message.ProcessingStartedAt()),
+		MessageWithHeaders(deadLetter.Message.Headers()),
+		MessageWithEventTime(deadLetter.Message.EventTime()),
+	)
+}
+
+// DeadLetterToChannel sends dead letters to a channel, as payloads of messages
+func DeadLetterToChannel(ch chan<- Message) DeadLetterSink {
+	return func(ctx context.Context, deadLetter DeadLetter) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ch <- newDeadLetterMessage(deadLetter):
+		}
+
+		return nil
+	}
+}
+
+// DeadLetterToLoader passes dead letters to a loader handler, as payloads of messages
+func DeadLetterToLoader(handler LoaderHandler) DeadLetterSink {
+	return func(ctx context.Context, deadLetter DeadLetter) error {
+		return handler(ctx, newDeadLetterMessage(deadLetter))
+	}
+}
+
+// sendToDeadLetterSink sends failed messages to the sink and acknowledges them. Returns false if there's no sink configured.
+func sendToDeadLetterSink(ctx context.Context, sink DeadLetterSink, stage string, msgs []Message, opErr error) (bool, error) {
+	if sink == nil {
+		return false, nil
+	}
+
+	var err error
+	for _, msg := range msgs {
+		err = sink(ctx, newDeadLetter(ctx, stage, msg, opErr))
+		if err != nil {
+			return true, err
+		}
+
+		err = msg.Ack(ctx)
+		if err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,103 @@
+package etl_test
+
+import (
+	"context"
+	"errors"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDeadLetter_LoaderSendsFailedMessagesToChannel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		errTest       = errors.New("test")
+		deadLetterCh  = make(chan etl.Message, 2)
+		onErrorCalled bool
+	)
+
+	extractor := etl.NewExtractor(newFakeExtractor(1, 2))
+	loader := etl.NewLoader(extractor.OutputCh(), func(ctx context.Context, message etl.Message) error {
+		if message.Payload() == 2 {
+			return errTest
+		}
+
+		return nil
+	},
+		etl.LoaderWithName("sink"),
+		etl.LoaderWithFailOnError(false),
+		etl.LoaderWithRetry(etl.RetryPolicy{MaxAttempts: 2, InitialInterval: time.Millisecond}),
+		etl.LoaderWithDeadLetterSink(etl.DeadLetterToChannel(deadLetterCh)),
+		etl.LoaderWithOnErrorHook(func(ctx context.Context, inMsg etl.Message, err error) error {
+			onErrorCalled = true
+			return nil
+		}),
+	)
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.NoError(t, err)
+	require.True(t, onErrorCalled)
+	require.Equal(t, 1, len(deadLetterCh))
+
+	deadLetter, ok := (<-deadLetterCh).Payload().(etl.DeadLetter)
+	require.True(t, ok)
+	require.Equal(t, 2, deadLetter.Message.Payload())
+	require.Equal(t, errTest, deadLetter.Err)
+	require.Equal(t, "sink", deadLetter.Stage)
+	require.Equal(t, 2, deadLetter.Attempts)
+	require.False(t, deadLetter.FailedAt.IsZero())
+}
+
+func TestDeadLetter_DeadLetteredMessagesAreAcked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hook := &fakeAckHook{}
+	deadLetters := &fakeLoader{}
+
+	p := etl.NewPipeline()
+	p.AddExtractor("src", newFakeExtractor(1, 2),
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	p.AddTransformer("double", func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+		return errors.New("test")
+	},
+		etl.TransformerWithFailOnError(false),
+		etl.TransformerWithDeadLetterSink(etl.DeadLetterToLoader(deadLetters.Handle)),
+	)
+	p.AddLoader("sink", func(ctx context.Context, message etl.Message) error { return nil })
+	p.From("src").To("double").To("sink")
+
+	err := p.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{1, 2}, hook.acked)
+	require.Empty(t, hook.nacked)
+	require.Equal(t, 2, len(deadLetters.calls))
+	require.Equal(t, "double", deadLetters.calls[0].Payload().(etl.DeadLetter).Stage)
+}
+
+func TestDeadLetter_LoaderBatchedReportsEveryMessage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deadLetters := &fakeLoader{}
+
+	extractor := etl.NewExtractor(newFakeExtractor(1, 2))
+	loader := etl.NewLoaderBatched(extractor.OutputCh(), func(ctx context.Context, messages []etl.Message) error {
+		return errors.New("test")
+	},
+		etl.LoaderBatchedWithFixedSizeBatches(2),
+		etl.LoaderBatchedWithFailOnError(false),
+		etl.LoaderBatchedWithDeadLetterSink(etl.DeadLetterToLoader(deadLetters.Handle)),
+	)
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(deadLetters.calls))
+	require.Equal(t, 1, deadLetters.calls[0].Payload().(etl.DeadLetter).Message.Payload())
+	require.Equal(t, 2, deadLetters.calls[1].Payload().(etl.DeadLetter).Message.Payload())
+}
@@ -0,0 +1,140 @@
+package etl
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// Deduplicator drops messages, which key has already been seen within the TTL. If message is negatively
+// acknowledged, its key is forgotten, so it can be redelivered.
+type Deduplicator interface {
+	Runner
+	OutputCh() <-chan Message
+}
+
+type deduplicator struct {
+	keyFn PartitionKeyFunc
+	ttl   time.Duration
+
+	inputCh  <-chan Message
+	outputCh chan Message
+
+	opts *deduplicatorOptions
+}
+
+func NewDeduplicator(inputCh <-chan Message, keyFn PartitionKeyFunc, ttl time.Duration, optsSetters ...DeduplicatorOption) Deduplicator {
+	opts := newDeduplicatorOptions(optsSetters...)
+
+	return &deduplicator{
+		keyFn: keyFn,
+		ttl:   ttl,
+
+		inputCh:  inputCh,
+		outputCh: make(chan Message, opts.outputChannelBufferSize),
+
+		opts: opts,
+	}
+}
+
+func (d *deduplicator) OutputCh() <-chan Message {
+	return d.outputCh
+}
+
+func (d *deduplicator) preRunHooks(ctx context.Context) error {
+	var err error
+	for _, hook := range d.opts.hooksPreRun {
+		err = hook(ctx, d.inputCh)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *deduplicator) onDuplicateHook(ctx context.Context, msg Message, key string) error {
+	var err error
+	for _, hook := range d.opts.hooksOnDuplicate {
+		err = hook(ctx, msg, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run deduplicates messages, until input channel is closed. Note that execution of this function is blocking, until processing is finished.
+func (d *deduplicator) Run(ctx context.Context) error {
+	err := d.preRunHooks(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to run deduplicator preRunHooks")
+	}
+
+	defer close(d.outputCh)
+
+	var (
+		inMsg Message
+		ok    bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case inMsg, ok = <-d.inputCh:
+			if !ok {
+				return nil
+			}
+
+			err = d.process(ctx, inMsg)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (d *deduplicator) process(ctx context.Context, inMsg Message) error {
+	key := d.keyFn(inMsg)
+
+	added, err := d.opts.store.Add(ctx, key, d.ttl)
+	if err != nil {
+		return errors.Wrap(err, "failed to add key to deduplicator store")
+	}
+
+	if !added {
+		err = d.onDuplicateHook(ctx, inMsg, key)
+		if err != nil {
+			return errors.Wrap(err, "failed to run deduplicator onDuplicate hook")
+		}
+
+		err = inMsg.Ack(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to ack duplicated message")
+		}
+
+		return nil
+	}
+
+	tracker := newAckTracker(func(ctx context.Context, err error) error {
+		if err == nil {
+			return inMsg.Ack(ctx)
+		}
+
+		removeErr := d.opts.store.Remove(ctx, key)
+		if removeErr != nil {
+			return errors.Wrap(removeErr, "failed to remove key from deduplicator store")
+		}
+
+		return inMsg.Nack(ctx, err)
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case d.outputCh <- withAckTracker(inMsg, tracker):
+	}
+
+	return nil
+}
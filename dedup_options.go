@@ -0,0 +1,51 @@
+package etl
+
+import "context"
+
+type deduplicatorOptions struct {
+	hooksPreRun      []DeduplicatorPreRunHook
+	hooksOnDuplicate []DeduplicatorOnDuplicateHook
+
+	store                   DeduplicatorStore
+	outputChannelBufferSize int
+}
+
+func newDeduplicatorOptions(optsSetters ...DeduplicatorOption) *deduplicatorOptions {
+	opts := &deduplicatorOptions{}
+
+	for _, setter := range optsSetters {
+		if setter != nil {
+			setter(opts)
+		}
+	}
+
+	if opts.store == nil {
+		opts.store = NewMemoryDeduplicatorStore(0)
+	}
+
+	return opts
+}
+
+type DeduplicatorOption func(o *deduplicatorOptions)
+
+type DeduplicatorPreRunHook func(ctx context.Context, inputCh <-chan Message) error
+
+func DeduplicatorWithPreRunHook(hook DeduplicatorPreRunHook) DeduplicatorOption {
+	return func(o *deduplicatorOptions) { o.hooksPreRun = append(o.hooksPreRun, hook) }
+}
+
+// DeduplicatorOnDuplicateHook is called for every dropped duplicate, before it's acknowledged
+type DeduplicatorOnDuplicateHook func(ctx context.Context, msg Message, key string) error
+
+func DeduplicatorWithOnDuplicateHook(hook DeduplicatorOnDuplicateHook) DeduplicatorOption {
+	return func(o *deduplicatorOptions) { o.hooksOnDuplicate = append(o.hooksOnDuplicate, hook) }
+}
+
+// DeduplicatorWithStore sets store of seen keys. By default, keys are kept in memory without a limit
+func DeduplicatorWithStore(store DeduplicatorStore) DeduplicatorOption {
+	return func(o *deduplicatorOptions) { o.store = store }
+}
+
+func DeduplicatorWithOutputChannelBufferSize(size int) DeduplicatorOption {
+	return func(o *deduplicatorOptions) { o.outputChannelBufferSize = size }
+}
@@ -0,0 +1,338 @@
+package etl
+
+import (
+	"bufio"
+	"container/heap"
+	"container/list"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeduplicatorStore records keys of messages seen by a Deduplicator. Implementations must be safe for concurrent use.
+type DeduplicatorStore interface {
+	// Add records key for ttl. Returns false if key has already been recorded and hasn't expired yet
+	Add(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Remove forgets key, so message with the same key is not treated as a duplicate
+	Remove(ctx context.Context, key string) error
+	Close() error
+}
+
+type memoryDeduplicatorEntry struct {
+	key       string
+	expiresAt time.Time
+	element   *list.Element
+	heapIndex int
+}
+
+// memoryDeduplicatorExpiries orders entries by expiration time, so expired entries are found regardless of their TTLs
+type memoryDeduplicatorExpiries []*memoryDeduplicatorEntry
+
+func (h memoryDeduplicatorExpiries) Len() int {
+	return len(h)
+}
+
+func (h memoryDeduplicatorExpiries) Less(i, j int) bool {
+	return h[i].expiresAt.Before(h[j].expiresAt)
+}
+
+func (h memoryDeduplicatorExpiries) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *memoryDeduplicatorExpiries) Push(x interface{}) {
+	entry := x.(*memoryDeduplicatorEntry)
+	entry.heapIndex = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *memoryDeduplicatorExpiries) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+
+	return entry
+}
+
+// memoryDeduplicatorStore keeps keys in a list ordered by recency of use, so least recently used keys are evicted
+// from its back once it's full. Expired keys are evicted in order of expiration
+type memoryDeduplicatorStore struct {
+	sync.Mutex
+	capacity int
+	entries  *list.List
+	expiries memoryDeduplicatorExpiries
+	index    map[string]*memoryDeduplicatorEntry
+}
+
+// NewMemoryDeduplicatorStore creates a store keeping up to capacity keys in memory, evicting least recently used ones
+// once it's full. Key is used whenever it's added, also as a duplicate. Capacity lower than 1 means no limit.
+func NewMemoryDeduplicatorStore(capacity int) DeduplicatorStore {
+	return newMemoryDeduplicatorStore(capacity)
+}
+
+func newMemoryDeduplicatorStore(capacity int) *memoryDeduplicatorStore {
+	return &memoryDeduplicatorStore{
+		capacity: capacity,
+		entries:  list.New(),
+		index:    make(map[string]*memoryDeduplicatorEntry),
+	}
+}
+
+func (s *memoryDeduplicatorStore) Add(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.add(key, time.Now().Add(ttl), time.Now()), nil
+}
+
+func (s *memoryDeduplicatorStore) add(key string, expiresAt, now time.Time) bool {
+	s.evictExpired(now)
+
+	if entry, ok := s.index[key]; ok {
+		// expired entries have already been evicted, so it's a duplicate
+		s.entries.MoveToFront(entry.element)
+
+		return false
+	}
+
+	entry := &memoryDeduplicatorEntry{key: key, expiresAt: expiresAt}
+	entry.element = s.entries.PushFront(entry)
+	heap.Push(&s.expiries, entry)
+	s.index[key] = entry
+
+	if s.capacity > 0 && s.entries.Len() > s.capacity {
+		s.remove(s.entries.Back().Value.(*memoryDeduplicatorEntry).key)
+	}
+
+	return true
+}
+
+func (s *memoryDeduplicatorStore) evictExpired(now time.Time) {
+	for len(s.expiries) > 0 && !s.expiries[0].expiresAt.After(now) {
+		s.remove(s.expiries[0].key)
+	}
+}
+
+func (s *memoryDeduplicatorStore) Remove(_ context.Context, key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.remove(key)
+
+	return nil
+}
+
+func (s *memoryDeduplicatorStore) remove(key string) {
+	if entry, ok := s.index[key]; ok {
+		s.entries.Remove(entry.element)
+		heap.Remove(&s.expiries, entry.heapIndex)
+		delete(s.index, key)
+	}
+}
+
+func (s *memoryDeduplicatorStore) Close() error {
+	return nil
+}
+
+const (
+	fileDeduplicatorAdd    = "+"
+	fileDeduplicatorRemove = "-"
+
+	fileDeduplicatorMinCompaction = 1024
+)
+
+// fileDeduplicatorStore keeps keys in memory, and logs every change to a file, which is replayed on start. Log is
+// compacted once it grows twice as big as the number of keys
+type fileDeduplicatorStore struct {
+	sync.Mutex
+	memory  *memoryDeduplicatorStore
+	path    string
+	file    *os.File
+	records int
+}
+
+// NewFileDeduplicatorStore creates a store, which survives restarts by logging keys to a file. Keys are kept in
+// memory as well, up to capacity. Capacity lower than 1 means no limit. Changes are not synced to disk, so they
+// survive process crashes, but not system ones.
+func NewFileDeduplicatorStore(path string, capacity int) (DeduplicatorStore, error) {
+	s := &fileDeduplicatorStore{
+		memory: newMemoryDeduplicatorStore(capacity),
+		path:   path,
+	}
+
+	err := s.replay()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to replay deduplicator store %q", path)
+	}
+
+	err = s.compact()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to compact deduplicator store %q", path)
+	}
+
+	return s, nil
+}
+
+func (s *fileDeduplicatorStore) replay() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var (
+		now     = time.Now()
+		scanner = bufio.NewScanner(f)
+	)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), " ", 3)
+
+		switch {
+		case len(fields) == 3 && fields[0] == fileDeduplicatorAdd:
+			expiresAt, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return err
+			}
+
+			key, err := strconv.Unquote(fields[2])
+			if err != nil {
+				return err
+			}
+
+			s.memory.remove(key)
+			if time.Unix(0, expiresAt).After(now) {
+				s.memory.add(key, time.Unix(0, expiresAt), now)
+			}
+		case len(fields) == 2 && fields[0] == fileDeduplicatorRemove:
+			key, err := strconv.Unquote(fields[1])
+			if err != nil {
+				return err
+			}
+
+			s.memory.remove(key)
+		default:
+			return fmt.Errorf("malformed record %q", scanner.Text())
+		}
+	}
+
+	return scanner.Err()
+}
+
+// compact rewrites log, so it contains only keys which haven't expired
+func (s *fileDeduplicatorStore) compact() error {
+	if s.file != nil {
+		err := s.file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	s.memory.evictExpired(time.Now())
+
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	for el := s.memory.entries.Back(); el != nil; el = el.Prev() {
+		entry := el.Value.(*memoryDeduplicatorEntry)
+
+		_, err = fmt.Fprintf(w, "%s %d %s\n", fileDeduplicatorAdd, entry.expiresAt.UnixNano(), strconv.Quote(entry.key))
+		if err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+
+	err = w.Flush()
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpPath, s.path)
+	if err != nil {
+		return err
+	}
+
+	s.file, err = os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	s.records = s.memory.entries.Len()
+
+	return nil
+}
+
+func (s *fileDeduplicatorStore) write(record string) error {
+	_, err := s.file.WriteString(record + "\n")
+	if err != nil {
+		return err
+	}
+
+	s.records++
+	if s.records > fileDeduplicatorMinCompaction && s.records > 2*s.memory.entries.Len() {
+		return s.compact()
+	}
+
+	return nil
+}
+
+func (s *fileDeduplicatorStore) Add(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var (
+		now       = time.Now()
+		expiresAt = now.Add(ttl)
+	)
+	if !s.memory.add(key, expiresAt, now) {
+		return false, nil
+	}
+
+	err := s.write(fmt.Sprintf("%s %d %s", fileDeduplicatorAdd, expiresAt.UnixNano(), strconv.Quote(key)))
+	if err != nil {
+		return true, errors.Wrap(err, "failed to write deduplicator store")
+	}
+
+	return true, nil
+}
+
+func (s *fileDeduplicatorStore) Remove(_ context.Context, key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.memory.remove(key)
+
+	err := s.write(fmt.Sprintf("%s %s", fileDeduplicatorRemove, strconv.Quote(key)))
+	if err != nil {
+		return errors.Wrap(err, "failed to write deduplicator store")
+	}
+
+	return nil
+}
+
+func (s *fileDeduplicatorStore) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.file.Close()
+}
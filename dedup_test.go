@@ -0,0 +1,178 @@
+package etl_test
+
+import (
+	"context"
+	"errors"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func keyFromPayload(msg etl.Message) string {
+	return msg.Payload().(string)
+}
+
+func TestDeduplicator_DropsDuplicates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var duplicates []string
+	hook := &fakeAckHook{}
+	extractor := etl.NewExtractor(newFakeExtractor("a", "b", "a", "c", "b"),
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	deduplicator := etl.NewDeduplicator(extractor.OutputCh(), keyFromPayload, time.Hour,
+		etl.DeduplicatorWithOnDuplicateHook(func(ctx context.Context, msg etl.Message, key string) error {
+			duplicates = append(duplicates, key)
+			return nil
+		}),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(deduplicator.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor, deduplicator, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"a", "b", "c"}, payloadsOf(l))
+	require.Equal(t, []string{"a", "b"}, duplicates)
+	require.Len(t, hook.acked, 5)
+}
+
+func TestDeduplicator_ForgetsNackedMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	inputCh := make(chan etl.Message)
+	deduplicator := etl.NewDeduplicator(inputCh, keyFromPayload, time.Hour)
+	go func() {
+		_ = deduplicator.Run(ctx)
+	}()
+
+	inputCh <- etl.NewMessage("a")
+	msg := <-deduplicator.OutputCh()
+	require.NoError(t, msg.Nack(ctx, errors.New("test")))
+
+	inputCh <- etl.NewMessage("a")
+	msg = <-deduplicator.OutputCh()
+	require.NoError(t, msg.Ack(ctx))
+
+	inputCh <- etl.NewMessage("a")
+	close(inputCh)
+	_, ok := <-deduplicator.OutputCh()
+	require.False(t, ok, "expected acknowledged message to be deduplicated")
+}
+
+func TestMemoryDeduplicatorStore(t *testing.T) {
+	ctx := context.Background()
+	store := etl.NewMemoryDeduplicatorStore(2)
+
+	for _, tt := range []struct {
+		key  string
+		ttl  time.Duration
+		want bool
+	}{
+		{key: "a", ttl: time.Hour, want: true},
+		{key: "a", ttl: time.Hour, want: false},
+		{key: "b", ttl: time.Millisecond, want: true},
+		{key: "c", ttl: time.Hour, want: true},
+		// least recently used key has been evicted
+		{key: "a", ttl: time.Hour, want: true},
+	} {
+		added, err := store.Add(ctx, tt.key, tt.ttl)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, added, "key %q", tt.key)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	added, err := store.Add(ctx, "b", time.Hour)
+	require.NoError(t, err)
+	require.True(t, added, "expected expired key to be added")
+}
+
+func TestMemoryDeduplicatorStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	store := etl.NewMemoryDeduplicatorStore(2)
+
+	for _, tt := range []struct {
+		key  string
+		want bool
+	}{
+		{key: "a", want: true},
+		{key: "b", want: true},
+		// duplicate is a use of the key
+		{key: "a", want: false},
+		{key: "c", want: true},
+		{key: "a", want: false},
+		// least recently used key has been evicted
+		{key: "b", want: true},
+	} {
+		added, err := store.Add(ctx, tt.key, time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, added, "key %q", tt.key)
+	}
+}
+
+func TestMemoryDeduplicatorStore_ExpiresKeysWithDifferentTTLs(t *testing.T) {
+	ctx := context.Background()
+	store := etl.NewMemoryDeduplicatorStore(2)
+
+	for _, tt := range []struct {
+		key  string
+		ttl  time.Duration
+		want bool
+	}{
+		{key: "a", ttl: time.Hour, want: true},
+		{key: "b", ttl: time.Millisecond, want: true},
+	} {
+		added, err := store.Add(ctx, tt.key, tt.ttl)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, added, "key %q", tt.key)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	added, err := store.Add(ctx, "c", time.Hour)
+	require.NoError(t, err)
+	require.True(t, added)
+
+	// expired key makes room, even though it's been added after a key with longer TTL
+	added, err = store.Add(ctx, "a", time.Hour)
+	require.NoError(t, err)
+	require.False(t, added)
+}
+
+func TestFileDeduplicatorStore_SurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "dedup.log")
+
+	store, err := etl.NewFileDeduplicatorStore(path, 0)
+	require.NoError(t, err)
+
+	for _, key := range []string{"a", "b", "with space\n"} {
+		_, err = store.Add(ctx, key, time.Hour)
+		require.NoError(t, err)
+	}
+	_, err = store.Add(ctx, "expired", time.Millisecond)
+	require.NoError(t, err)
+	require.NoError(t, store.Remove(ctx, "b"))
+	// enough records to trigger compaction
+	for i := 0; i < 2000; i++ {
+		_, err = store.Add(ctx, "c", time.Hour)
+		require.NoError(t, err)
+		require.NoError(t, store.Remove(ctx, "c"))
+	}
+	require.NoError(t, store.Close())
+
+	time.Sleep(2 * time.Millisecond)
+	store, err = etl.NewFileDeduplicatorStore(path, 0)
+	require.NoError(t, err)
+	defer store.Close()
+
+	for key, want := range map[string]bool{"a": false, "with space\n": false, "b": true, "c": true, "expired": true} {
+		added, err := store.Add(ctx, key, time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, want, added, "key %q", key)
+	}
+}
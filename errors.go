@@ -5,4 +5,24 @@ import "errors"
 var (
 	ErrCastingFailed                   = errors.New("casting incomming message failed")
 	ErrOutputMessageOutOfChannelsRange = errors.New("tried to get an output chan out of range")
+	ErrMessageNacked                   = errors.New("message has been negatively acknowledged")
+	ErrGroupAlreadyRunning             = errors.New("group can be run only once")
+
+	ErrPipelineEmpty               = errors.New("pipeline has no stages")
+	ErrPipelineDuplicatedStage     = errors.New("pipeline stage with the same name already exists")
+	ErrPipelineStageNotFound       = errors.New("pipeline stage not found")
+	ErrPipelineStageHasNoOutput    = errors.New("pipeline stage does not produce output")
+	ErrPipelineStageHasNoInput     = errors.New("pipeline stage does not accept input")
+	ErrPipelineOutputConsumedTwice = errors.New("pipeline stage output is connected to more than one stage")
+	ErrPipelineStageConsumesTwice  = errors.New("pipeline stage is connected to more than one input")
+	ErrPipelineMissingInput        = errors.New("pipeline stage input is not connected")
+	ErrPipelineDanglingOutput      = errors.New("pipeline stage output is not connected")
+	ErrPipelineUnreachableStage    = errors.New("pipeline stage is not reachable from any extractor")
+
+	ErrRouterRouteNotFound   = errors.New("router route not found")
+	ErrRouterDuplicatedRoute = errors.New("router route with the same name already exists")
+
+	ErrReducerMergeMissing = errors.New("reducer has to implement Merge to spill aggregates")
+
+	ErrWindowSizeNotPositive = errors.New("window size, slide and gap have to be positive")
 )
@@ -3,12 +3,14 @@ package etl
 import (
 	"context"
 	"github.com/pkg/errors"
+	"sync"
 )
 
 type ExtractorHandler func(ctx context.Context, sender Sender) error
 
 type Extractor interface {
 	Runner
+	Stopper
 	OutputCh() <-chan Message
 }
 
@@ -16,6 +18,9 @@ type extractor struct {
 	handler  ExtractorHandler
 	outputCh chan Message
 
+	stopCh   chan struct{}
+	stopOnce sync.Once
+
 	opts *extractorOptions
 }
 
@@ -23,8 +28,9 @@ func NewExtractor(handler ExtractorHandler, optsSetters ...ExtractorOption) Extr
 	opts := newExtractorOptions(optsSetters...)
 
 	return &extractor{
-		handler:  handler,
+		handler:  wrapExtractorHandler(handler, opts.middlewares),
 		outputCh: make(chan Message, opts.outputChannelBufferSize),
+		stopCh:   make(chan struct{}),
 		opts:     opts,
 	}
 }
@@ -45,14 +51,104 @@ func (e *extractor) preRunHooks(ctx context.Context) error {
 	return nil
 }
 
+func (e *extractor) onAckHook(ctx context.Context, msg Message) error {
+	var err error
+	for _, hook := range e.opts.hooksOnAck {
+		err = hook(ctx, msg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *extractor) onNackHook(ctx context.Context, msg Message, opErr error) error {
+	var err error
+	for _, hook := range e.opts.hooksOnNack {
+		err = hook(ctx, msg, opErr)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tracker attaches acknowledgement tracking to sent messages, if there are any hooks interested in it
+func (e *extractor) tracker() senderTracker {
+	if len(e.opts.hooksOnAck) == 0 && len(e.opts.hooksOnNack) == 0 {
+		return nil
+	}
+
+	return func(msg Message) Message {
+		return withAckTracker(msg, newAckTracker(func(ctx context.Context, err error) error {
+			if err != nil {
+				return e.onNackHook(ctx, msg, err)
+			}
+
+			return e.onAckHook(ctx, msg)
+		}))
+	}
+}
+
+// stamp attaches watermarks and acknowledgement tracking to sent messages, if they're enabled
+func (e *extractor) stamp() senderTracker {
+	tracker := e.tracker()
+	if !e.opts.watermarks {
+		return tracker
+	}
+
+	generator := &watermarkGenerator{maxOutOfOrderness: e.opts.maxOutOfOrderness}
+
+	return func(msg Message) Message {
+		msg = generator.stamp(msg)
+		if tracker != nil {
+			msg = tracker(msg)
+		}
+
+		return msg
+	}
+}
+
+// Stop cancels context passed to the handler. Once handler returns, output channel is closed and Run returns
+// without an error, so the rest of the pipeline can drain
+func (e *extractor) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stopCh)
+	})
+}
+
+func (e *extractor) stopped() bool {
+	select {
+	case <-e.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
 func (e *extractor) Run(ctx context.Context) error {
 	err := e.preRunHooks(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to run extractor preRunHooks")
 	}
 
-	err = e.handler(ctx, newSender([]chan Message{e.outputCh}, nil, nil))
-	if err != nil {
+	handlerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-e.stopCh:
+			cancel()
+		case <-handlerCtx.Done():
+		}
+	}()
+
+	err = callRecovering(func() error {
+		return e.handler(handlerCtx, newSender([]chan Message{e.outputCh}, nil, nil, e.stamp(), nil))
+	})
+	if err != nil && !(e.stopped() && ctx.Err() == nil && errors.Is(err, context.Canceled)) {
 		return err
 	}
 
@@ -1,10 +1,19 @@
 package etl
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type extractorOptions struct {
 	hooksPreRun             []ExtractorPreRunHook
+	hooksOnAck              []ExtractorOnAckHook
+	hooksOnNack             []ExtractorOnNackHook
+	middlewares             []ExtractorMiddleware
 	outputChannelBufferSize int
+
+	watermarks        bool
+	maxOutOfOrderness time.Duration
 }
 
 func newExtractorOptions(optsSetters ...ExtractorOption) *extractorOptions {
@@ -34,3 +43,40 @@ func ExtractorWithOutputChannelBufferSize(size int) ExtractorOption {
 		o.outputChannelBufferSize = size
 	}
 }
+
+// ExtractorOnAckHook is called once message sent by extractor, and all messages derived from it, have been
+// acknowledged by loaders. It might be called concurrently.
+type ExtractorOnAckHook func(ctx context.Context, msg Message) error
+
+func ExtractorWithOnAckHook(hook ExtractorOnAckHook) ExtractorOption {
+	return func(o *extractorOptions) {
+		o.hooksOnAck = append(o.hooksOnAck, hook)
+	}
+}
+
+// ExtractorOnNackHook is called once message sent by extractor, or any message derived from it, has been negatively
+// acknowledged. It might be called concurrently.
+type ExtractorOnNackHook func(ctx context.Context, msg Message, err error) error
+
+func ExtractorWithOnNackHook(hook ExtractorOnNackHook) ExtractorOption {
+	return func(o *extractorOptions) {
+		o.hooksOnNack = append(o.hooksOnNack, hook)
+	}
+}
+
+// ExtractorWithWatermarks stamps sent messages with a watermark, trailing the latest event time seen by
+// maxOutOfOrderness. Messages are expected to arrive at most maxOutOfOrderness out of order, with respect to their
+// event time.
+func ExtractorWithWatermarks(maxOutOfOrderness time.Duration) ExtractorOption {
+	return func(o *extractorOptions) {
+		o.watermarks = true
+		o.maxOutOfOrderness = maxOutOfOrderness
+	}
+}
+
+// ExtractorWithMiddleware wraps handler with a middleware. The first middleware is the outermost one.
+func ExtractorWithMiddleware(middleware ExtractorMiddleware) ExtractorOption {
+	return func(o *extractorOptions) {
+		o.middlewares = append(o.middlewares, middleware)
+	}
+}
@@ -0,0 +1,56 @@
+package etl
+
+import "context"
+
+type outgoingHeadersCtxKey struct{}
+
+// ContextWithOutgoingHeaders returns a context, which adds headers to all messages sent with it, overriding existing
+// values. It allows handler middlewares to attach e.g. trace context to messages sent by handlers.
+func ContextWithOutgoingHeaders(ctx context.Context, headers map[string]string) context.Context {
+	merged := make(map[string]string, len(headers))
+	for key, value := range outgoingHeadersFromContext(ctx) {
+		merged[key] = value
+	}
+	for key, value := range headers {
+		merged[key] = value
+	}
+
+	return context.WithValue(ctx, outgoingHeadersCtxKey{}, merged)
+}
+
+func outgoingHeadersFromContext(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(outgoingHeadersCtxKey{}).(map[string]string)
+
+	return headers
+}
+
+type headeredMessage struct {
+	Message
+	headers map[string]string
+}
+
+func (m *headeredMessage) Header(key string) string {
+	if value, ok := m.headers[key]; ok {
+		return value
+	}
+
+	return m.Message.Header(key)
+}
+
+func (m *headeredMessage) Headers() map[string]string {
+	headers := m.Message.Headers()
+	for key, value := range m.headers {
+		headers[key] = value
+	}
+
+	return headers
+}
+
+// withHeaders overrides headers of a message
+func withHeaders(msg Message, headers map[string]string) Message {
+	if len(headers) == 0 {
+		return msg
+	}
+
+	return &headeredMessage{Message: msg, headers: headers}
+}
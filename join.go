@@ -0,0 +1,382 @@
+package etl
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"sort"
+	"time"
+)
+
+// JoinHandler is called for every pair of matched messages. For unmatched messages, passed to handler according to
+// join type, the other message is nil
+type JoinHandler func(ctx context.Context, left, right Message, sender Sender) error
+
+// Join matches messages from two inputs by key, if their timestamps are not further apart than the join window.
+// Messages are buffered, until the watermark passes their timestamp by the join window, or both inputs are closed.
+// Until messages carry watermarks, wall clock is used instead.
+type Join interface {
+	Runner
+	OutputCh() <-chan Message
+}
+
+type joinEntry struct {
+	msg     Message
+	t       time.Time
+	matched bool
+	left    bool
+	tracker *ackTracker
+}
+
+type joinSide struct {
+	inputCh   <-chan Message
+	key       PartitionKeyFunc
+	entries   map[string][]*joinEntry
+	watermark time.Time
+	closed    bool
+}
+
+type join struct {
+	handler JoinHandler
+	window  time.Duration
+
+	left     *joinSide
+	right    *joinSide
+	outputCh chan Message
+
+	opts *joinOptions
+}
+
+func NewJoin(leftCh, rightCh <-chan Message, leftKey, rightKey PartitionKeyFunc, window time.Duration, handler JoinHandler, optsSetters ...JoinOption) Join {
+	opts := newJoinOptions(optsSetters...)
+
+	return &join{
+		handler: handler,
+		window:  window,
+
+		left:     &joinSide{inputCh: leftCh, key: leftKey, entries: make(map[string][]*joinEntry)},
+		right:    &joinSide{inputCh: rightCh, key: rightKey, entries: make(map[string][]*joinEntry)},
+		outputCh: make(chan Message, opts.outputChannelBufferSize),
+
+		opts: opts,
+	}
+}
+
+func (j *join) OutputCh() <-chan Message {
+	return j.outputCh
+}
+
+func (j *join) preRunHooks(ctx context.Context) error {
+	var err error
+	for _, hook := range j.opts.hooksPreRun {
+		err = hook(ctx, j.left.inputCh, j.right.inputCh)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (j *join) onErrorHook(ctx context.Context, left, right Message, opErr error) error {
+	var err error
+	for _, hook := range j.opts.hooksOnError {
+		err = hook(ctx, left, right, opErr)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run joins messages, until both input channels are closed. Note that execution of this function is blocking, until processing is finished.
+func (j *join) Run(ctx context.Context) error {
+	err := j.preRunHooks(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to run join preRunHooks")
+	}
+
+	defer close(j.outputCh)
+
+	ticker := time.NewTicker(j.opts.tick)
+	defer ticker.Stop()
+
+	var (
+		leftCh  = j.left.inputCh
+		rightCh = j.right.inputCh
+		inMsg   Message
+		ok      bool
+		now     time.Time
+	)
+	for !j.left.closed || !j.right.closed {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case inMsg, ok = <-leftCh:
+			if !ok {
+				j.left.closed, leftCh = true, nil
+				continue
+			}
+
+			err = j.receive(ctx, j.left, j.right, inMsg)
+		case inMsg, ok = <-rightCh:
+			if !ok {
+				j.right.closed, rightCh = true, nil
+				continue
+			}
+
+			err = j.receive(ctx, j.right, j.left, inMsg)
+		case now = <-ticker.C:
+			if j.watermark().IsZero() {
+				err = j.evict(ctx, j.expiredAt(now))
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return j.evict(ctx, func(*joinEntry) bool { return true })
+}
+
+// watermark returns watermark of the join, which is the lower one of both inputs
+func (j *join) watermark() time.Time {
+	if j.left.closed {
+		return j.right.watermark
+	}
+
+	if j.right.closed || j.right.watermark.After(j.left.watermark) {
+		return j.left.watermark
+	}
+
+	return j.right.watermark
+}
+
+// expiredAt returns a filter of entries, which can't be matched by messages newer than the time
+func (j *join) expiredAt(t time.Time) func(entry *joinEntry) bool {
+	return func(entry *joinEntry) bool {
+		return t.After(entry.t.Add(j.window))
+	}
+}
+
+// receive matches message with buffered messages from the other input, and buffers it
+func (j *join) receive(ctx context.Context, side, other *joinSide, inMsg Message) error {
+	entry := &joinEntry{
+		msg:  inMsg,
+		t:    j.opts.timestamp(inMsg),
+		left: side == j.left,
+		tracker: newAckTracker(func(ctx context.Context, err error) error {
+			if err != nil {
+				return inMsg.Nack(ctx, err)
+			}
+
+			return inMsg.Ack(ctx)
+		}),
+	}
+	// tracker is held while message is buffered
+	entry.tracker.add()
+
+	var (
+		key = side.key(inMsg)
+		err error
+	)
+	for _, otherEntry := range other.entries[key] {
+		if entry.t.Sub(otherEntry.t) > j.window || otherEntry.t.Sub(entry.t) > j.window {
+			continue
+		}
+
+		entry.matched, otherEntry.matched = true, true
+		if entry.left {
+			err = j.process(ctx, entry, otherEntry)
+		} else {
+			err = j.process(ctx, otherEntry, entry)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	side.entries[key] = append(side.entries[key], entry)
+
+	if inMsg.Watermark().After(side.watermark) {
+		side.watermark = inMsg.Watermark()
+	}
+
+	if watermark := j.watermark(); !watermark.IsZero() {
+		return j.evict(ctx, j.expiredAt(watermark))
+	}
+
+	return nil
+}
+
+// evict removes buffered messages matching the filter, in order of their timestamps. Unmatched messages are passed
+// to handler or side output, according to join type
+func (j *join) evict(ctx context.Context, filter func(entry *joinEntry) bool) error {
+	var evicted []*joinEntry
+	for _, side := range []*joinSide{j.left, j.right} {
+		for key, entries := range side.entries {
+			var kept []*joinEntry
+			for _, entry := range entries {
+				if filter(entry) {
+					evicted = append(evicted, entry)
+				} else {
+					kept = append(kept, entry)
+				}
+			}
+
+			if len(kept) == 0 {
+				delete(side.entries, key)
+			} else {
+				side.entries[key] = kept
+			}
+		}
+	}
+
+	sort.SliceStable(evicted, func(i, k int) bool { return evicted[i].t.Before(evicted[k].t) })
+
+	var err error
+	for _, entry := range evicted {
+		err = j.evictEntry(ctx, entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (j *join) evictEntry(ctx context.Context, entry *joinEntry) error {
+	var err error
+	switch {
+	case entry.matched:
+	case entry.left && j.opts.joinType != JoinInner:
+		err = j.process(ctx, entry, nil)
+	case !entry.left && j.opts.joinType == JoinOuter:
+		err = j.process(ctx, nil, entry)
+	case j.opts.unmatchedOutput != nil:
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case j.opts.unmatchedOutput <- withAckTracker(entry.msg, entry.tracker):
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	err = entry.tracker.release(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to ack evicted join message")
+	}
+
+	return nil
+}
+
+func (j *join) newJoinSender(left, right Message, inMsgs []Message, tracker *ackTracker) sender {
+	var (
+		watermark = j.watermark()
+		opts      = []MessageOption{MessageWithProcessingStartedAt(inMsgs[0].ProcessingStartedAt())}
+		eventTime time.Time
+	)
+	for i := len(inMsgs) - 1; i >= 0; i-- {
+		opts = append(opts, MessageWithHeaders(inMsgs[i].Headers()))
+		if inMsgs[i].EventTime().After(eventTime) {
+			eventTime = inMsgs[i].EventTime()
+		}
+	}
+	opts = append(opts, MessageWithEventTime(eventTime))
+
+	return newSender(
+		[]chan Message{j.outputCh},
+		opts,
+		func(ctx context.Context, outMsg Message, _ uint) error {
+			var err error
+			for _, hook := range j.opts.hooksOnComplete {
+				err = hook(ctx, left, right, outMsg)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		func(msg Message) Message {
+			return withAckTracker(withWatermark(msg, watermark), tracker)
+		},
+		nil,
+	)
+}
+
+// process runs handler for a pair of messages, one of which might be nil. Returns an error only if processing
+// should be stopped.
+func (j *join) process(ctx context.Context, leftEntry, rightEntry *joinEntry) error {
+	var (
+		left, right Message
+		inMsgs      []Message
+	)
+	if leftEntry != nil {
+		left = withAckTracker(leftEntry.msg, leftEntry.tracker)
+		inMsgs = append(inMsgs, left)
+	}
+	if rightEntry != nil {
+		right = withAckTracker(rightEntry.msg, rightEntry.tracker)
+		inMsgs = append(inMsgs, right)
+	}
+
+	var (
+		tracker = newAckTracker(func(ctx context.Context, err error) error {
+			if err != nil {
+				return nackMessages(ctx, inMsgs, err)
+			}
+
+			return ackMessages(ctx, inMsgs)
+		})
+		sender = j.newJoinSender(left, right, inMsgs, tracker)
+
+		hookCtx context.Context
+		opErr   error
+		err     error
+
+		deadLettered bool
+	)
+	tracker.add()
+
+	hookCtx, opErr = runAttempts(ctx, j.opts.retryPolicy, sender, func(ctx context.Context, sender Sender) error {
+		ctx, cancel := contextWithHandlerTimeout(ctx, j.opts.handlerTimeout)
+		defer cancel()
+
+		return callRecovering(func() error {
+			return j.handler(ctx, left, right, sender)
+		}, inMsgs...)
+	})
+	if opErr != nil {
+		err = j.onErrorHook(hookCtx, left, right, opErr)
+		if err != nil {
+			return errors.Wrap(err, "running join on error hook has failed")
+		}
+
+		deadLettered, err = sendToDeadLetterSink(hookCtx, j.opts.deadLetterSink, j.opts.name, inMsgs, opErr)
+		if err != nil {
+			return errors.Wrap(err, "failed to send join messages to dead letter sink")
+		}
+
+		if !deadLettered {
+			err = tracker.release(ctx, opErr)
+			if err != nil {
+				return errors.Wrap(err, "failed to nack join messages")
+			}
+		}
+
+		if j.opts.failOnErr {
+			return opErr
+		}
+
+		return nil
+	}
+
+	err = tracker.release(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to ack join messages")
+	}
+
+	return nil
+}
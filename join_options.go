@@ -0,0 +1,132 @@
+package etl
+
+import (
+	"context"
+	"time"
+)
+
+// JoinType decides what happens with messages, which haven't been matched within the join window
+type JoinType int
+
+const (
+	// JoinInner emits only matched pairs of messages
+	JoinInner JoinType = iota
+	// JoinLeft emits matched pairs and unmatched messages from the left input
+	JoinLeft
+	// JoinOuter emits matched pairs and unmatched messages from both inputs
+	JoinOuter
+)
+
+type joinOptions struct {
+	hooksPreRun     []JoinPreRunHook
+	hooksOnError    []JoinOnErrorHook
+	hooksOnComplete []JoinOnComplete
+
+	retryPolicy    *RetryPolicy
+	deadLetterSink DeadLetterSink
+	handlerTimeout time.Duration
+
+	name                    string
+	outputChannelBufferSize int
+	failOnErr               bool
+
+	joinType        JoinType
+	timestamp       WindowTimestampFunc
+	unmatchedOutput chan<- Message
+	tick            time.Duration
+}
+
+func newJoinOptions(optsSetters ...JoinOption) *joinOptions {
+	opts := &joinOptions{
+		failOnErr: true,
+		joinType:  JoinInner,
+		timestamp: func(msg Message) time.Time { return msg.EventTime() },
+		tick:      time.Second,
+	}
+
+	for _, setter := range optsSetters {
+		if setter != nil {
+			setter(opts)
+		}
+	}
+
+	return opts
+}
+
+type JoinOption func(o *joinOptions)
+
+// JoinWithName sets name of the stage, used to describe it e.g. in dead letters
+func JoinWithName(name string) JoinOption {
+	return func(o *joinOptions) { o.name = name }
+}
+
+type JoinPreRunHook func(ctx context.Context, leftCh, rightCh <-chan Message) error
+
+func JoinWithPreRunHook(hook JoinPreRunHook) JoinOption {
+	return func(o *joinOptions) { o.hooksPreRun = append(o.hooksPreRun, hook) }
+}
+
+// JoinOnErrorHook is called when handler fails. One of messages is nil for unmatched messages
+type JoinOnErrorHook func(ctx context.Context, left, right Message, err error) error
+
+func JoinWithOnErrorHook(hook JoinOnErrorHook) JoinOption {
+	return func(o *joinOptions) { o.hooksOnError = append(o.hooksOnError, hook) }
+}
+
+// JoinOnComplete is called for every message sent by handler. One of messages is nil for unmatched messages
+type JoinOnComplete func(ctx context.Context, left, right Message, outMsg Message) error
+
+func JoinWithOnCompleteHook(hook JoinOnComplete) JoinOption {
+	return func(o *joinOptions) { o.hooksOnComplete = append(o.hooksOnComplete, hook) }
+}
+
+func JoinWithOutputChannelBufferSize(size int) JoinOption {
+	return func(o *joinOptions) { o.outputChannelBufferSize = size }
+}
+
+func JoinWithFailOnError(failOnErr bool) JoinOption {
+	return func(o *joinOptions) { o.failOnErr = failOnErr }
+}
+
+// JoinWithRetry retries failed handler calls according to the policy, before error hooks are called
+func JoinWithRetry(policy RetryPolicy) JoinOption {
+	return func(o *joinOptions) { o.retryPolicy = &policy }
+}
+
+// JoinWithDeadLetterSink routes messages that failed processing to the sink, after error hooks are called
+func JoinWithDeadLetterSink(sink DeadLetterSink) JoinOption {
+	return func(o *joinOptions) { o.deadLetterSink = sink }
+}
+
+// JoinWithHandlerTimeout limits duration of every handler call, by setting a deadline on its context. Handler is
+// expected to return once the context is done. Timed out calls are retried and reported as any other error
+func JoinWithHandlerTimeout(timeout time.Duration) JoinOption {
+	return func(o *joinOptions) { o.handlerTimeout = timeout }
+}
+
+// JoinWithType sets join semantics. Inner join is used by default
+func JoinWithType(joinType JoinType) JoinOption {
+	return func(o *joinOptions) { o.joinType = joinType }
+}
+
+// JoinWithTimestamp sets time of a message used to match it within the join window. By default, event time of
+// message is used
+func JoinWithTimestamp(timestampFn WindowTimestampFunc) JoinOption {
+	return func(o *joinOptions) { o.timestamp = timestampFn }
+}
+
+// JoinWithUnmatchedOutput sends messages evicted without a match, which are not passed to the handler according to
+// join type, to a side output channel. Otherwise, they're acknowledged and dropped
+func JoinWithUnmatchedOutput(ch chan<- Message) JoinOption {
+	return func(o *joinOptions) { o.unmatchedOutput = ch }
+}
+
+// JoinWithTick sets how often buffered messages are checked for eviction, until messages carry watermarks. Defaults
+// to a second, which is also kept when the tick isn't positive.
+func JoinWithTick(tick time.Duration) JoinOption {
+	return func(o *joinOptions) {
+		if tick > 0 {
+			o.tick = tick
+		}
+	}
+}
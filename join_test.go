@@ -0,0 +1,136 @@
+package etl_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func fakeJoinHandler(ctx context.Context, left, right etl.Message, sender etl.Sender) error {
+	describe := func(msg etl.Message) string {
+		if msg == nil {
+			return "-"
+		}
+
+		event := msg.Payload().(fakeEvent)
+		return fmt.Sprintf("%s@%d", event.user, event.at)
+	}
+
+	return sender.Send(ctx, describe(left)+"|"+describe(right))
+}
+
+func newFakeJoin(joinType etl.JoinType, unmatchedCh chan<- etl.Message, optsSetters ...etl.JoinOption) etl.Join {
+	optsSetters = append([]etl.JoinOption{
+		etl.JoinWithType(joinType),
+		etl.JoinWithTimestamp(fakeEventTimestamp),
+		etl.JoinWithUnmatchedOutput(unmatchedCh),
+	}, optsSetters...)
+
+	return etl.NewJoin(
+		newFilledChannel(fakeEvent{user: "a", at: 1}, fakeEvent{user: "b", at: 2}, fakeEvent{user: "c", at: 3}),
+		newFilledChannel(fakeEvent{user: "a", at: 2}, fakeEvent{user: "c", at: 20}),
+		fakeEventUser,
+		fakeEventUser,
+		5*time.Second,
+		fakeJoinHandler,
+		optsSetters...,
+	)
+}
+
+func TestJoin_Inner(t *testing.T) {
+	unmatchedCh := make(chan etl.Message, 10)
+	join := newFakeJoin(etl.JoinInner, unmatchedCh)
+
+	require.Equal(t, []interface{}{"a@1|a@2"}, runIntoLoader(t, join, join.OutputCh()))
+
+	close(unmatchedCh)
+	var unmatched []interface{}
+	for msg := range unmatchedCh {
+		unmatched = append(unmatched, msg.Payload())
+	}
+	require.Equal(t, []interface{}{
+		fakeEvent{user: "b", at: 2},
+		fakeEvent{user: "c", at: 3},
+		fakeEvent{user: "c", at: 20},
+	}, unmatched)
+}
+
+func TestJoin_Left(t *testing.T) {
+	unmatchedCh := make(chan etl.Message, 10)
+	join := newFakeJoin(etl.JoinLeft, unmatchedCh)
+
+	require.Equal(t, []interface{}{"a@1|a@2", "b@2|-", "c@3|-"}, runIntoLoader(t, join, join.OutputCh()))
+	require.Len(t, unmatchedCh, 1)
+}
+
+func TestJoin_Outer(t *testing.T) {
+	unmatchedCh := make(chan etl.Message, 10)
+	join := newFakeJoin(etl.JoinOuter, unmatchedCh)
+
+	require.Equal(t, []interface{}{"a@1|a@2", "b@2|-", "c@3|-", "-|c@20"}, runIntoLoader(t, join, join.OutputCh()))
+	require.Len(t, unmatchedCh, 0)
+}
+
+func TestJoin_IgnoresNonPositiveTick(t *testing.T) {
+	join := newFakeJoin(etl.JoinInner, make(chan etl.Message, 10), etl.JoinWithTick(-time.Second))
+
+	require.Equal(t, []interface{}{"a@1|a@2"}, runIntoLoader(t, join, join.OutputCh()))
+}
+
+func TestJoin_EvictsOnWatermark(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	leftCh := make(chan etl.Message)
+	rightCh := make(chan etl.Message)
+	join := etl.NewJoin(leftCh, rightCh, fakeEventUser, fakeEventUser, time.Second, fakeJoinHandler,
+		etl.JoinWithType(etl.JoinLeft),
+		etl.JoinWithTimestamp(fakeEventTimestamp),
+	)
+	go func() {
+		_ = join.Run(ctx)
+	}()
+
+	leftCh <- etl.NewMessage(fakeEvent{user: "a", at: 1}, etl.MessageWithWatermark(time.Unix(1, 0)))
+	rightCh <- etl.NewMessage(fakeEvent{user: "b", at: 5}, etl.MessageWithWatermark(time.Unix(5, 0)))
+	leftCh <- etl.NewMessage(fakeEvent{user: "c", at: 5}, etl.MessageWithWatermark(time.Unix(5, 0)))
+
+	select {
+	case msg := <-join.OutputCh():
+		require.Equal(t, "a@1|-", msg.Payload())
+	case <-time.After(time.Second):
+		require.Fail(t, "message has not been evicted")
+	}
+}
+
+func TestJoin_AcksAllMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hook := &fakeAckHook{}
+	left := etl.NewExtractor(newFakeExtractor(fakeEvent{user: "a", at: 1}, fakeEvent{user: "b", at: 1}),
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	right := etl.NewExtractor(newFakeExtractor(fakeEvent{user: "a", at: 3}),
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	join := etl.NewJoin(left.OutputCh(), right.OutputCh(), fakeEventUser, fakeEventUser, 5*time.Second, fakeJoinHandler,
+		etl.JoinWithTimestamp(fakeEventTimestamp),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(join.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, left, right, join, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"a@1|a@3"}, payloadsOf(l))
+	require.ElementsMatch(t, []interface{}{
+		fakeEvent{user: "a", at: 1},
+		fakeEvent{user: "b", at: 1},
+		fakeEvent{user: "a", at: 3},
+	}, hook.acked)
+}
@@ -24,7 +24,7 @@ func NewLoader(inputCh <-chan Message, handler LoaderHandler, optsSetters ...Loa
 	opts := newLoaderOptions(optsSetters...)
 
 	return &loader{
-		handler: handler,
+		handler: wrapLoaderHandler(handler, opts.middlewares),
 
 		inputCh: inputCh,
 
@@ -51,15 +51,37 @@ func (l *loader) Run(ctx context.Context) error {
 		return errors.Wrap(err, "failed to run batched loader preRunHooks")
 	}
 
+	if l.opts.partitionKey != nil {
+		return l.runPartitioned(ctx)
+	}
+
 	if l.opts.concurrency == 1 {
-		return l.runWorker(ctx)
+		return l.runWorker(ctx, l.inputCh)
 	}
 
 	g, ctx := errgroup.WithContext(ctx)
 
-	for i := 0; i <= l.opts.concurrency; i++ {
+	for i := 0; i < l.opts.concurrency; i++ {
+		g.Go(func() error {
+			return l.runWorker(ctx, l.inputCh)
+		})
+	}
+
+	return g.Wait()
+}
+
+// runPartitioned dispatches messages with the same partition key to the same worker
+func (l *loader) runPartitioned(ctx context.Context) error {
+	partitionChs, inputChs := newPartitionChannels(l.opts.concurrency)
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return partitionMessages(ctx, l.inputCh, l.opts.partitionKey, partitionChs)
+	})
+	for _, inputCh := range inputChs {
+		inputCh := inputCh
 		g.Go(func() error {
-			return l.runWorker(ctx)
+			return l.runWorker(ctx, inputCh)
 		})
 	}
 
@@ -90,39 +112,68 @@ func (l *loader) onCompleteHook(ctx context.Context, inMsg Message) error {
 	return nil
 }
 
-func (l *loader) runWorker(ctx context.Context) error {
+func (l *loader) runWorker(ctx context.Context, inputCh <-chan Message) error {
 	var (
-		inMsg Message
-		ok    bool
-		opErr error
-		err   error
+		inMsg   Message
+		ok      bool
+		hookCtx context.Context
+		opErr   error
+		err     error
+
+		deadLettered bool
 	)
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case inMsg, ok = <-l.inputCh:
+		case inMsg, ok = <-inputCh:
 			if !ok {
 				return nil
 			}
 
-			opErr = l.handler(ctx, inMsg)
+			hookCtx, opErr = l.opts.retryPolicy.run(ctx, func(ctx context.Context) error {
+				ctx, cancel := contextWithHandlerTimeout(ctx, l.opts.handlerTimeout)
+				defer cancel()
+
+				return callRecovering(func() error {
+					return l.handler(ctx, inMsg)
+				}, inMsg)
+			})
 			if opErr != nil {
-				err = l.onErrorHook(ctx, inMsg, opErr)
+				err = l.onErrorHook(hookCtx, inMsg, opErr)
 				if err != nil {
 					return errors.Wrap(err, "running loader on error hook has failed")
 				}
 
+				deadLettered, err = sendToDeadLetterSink(hookCtx, l.opts.deadLetterSink, l.opts.name, []Message{inMsg}, opErr)
+				if err != nil {
+					return errors.Wrap(err, "failed to send loader message to dead letter sink")
+				}
+
+				if !deadLettered {
+					err = inMsg.Nack(ctx, opErr)
+					if err != nil {
+						return errors.Wrap(err, "failed to nack loader message")
+					}
+				}
+
 				if l.opts.failOnErr {
 					return opErr
 				}
+
+				continue
 			}
 
-			err = l.onCompleteHook(ctx, inMsg)
+			err = l.onCompleteHook(hookCtx, inMsg)
 			if err != nil {
+				_ = inMsg.Nack(ctx, err)
 				return errors.Wrap(err, "failed to run loader onComplete hook")
 			}
 
+			err = inMsg.Ack(ctx)
+			if err != nil {
+				return errors.Wrap(err, "failed to ack loader message")
+			}
 		}
 	}
 }
@@ -24,7 +24,7 @@ func NewLoaderBatched(inputCh <-chan Message, handler LoaderBatchedHandler, opts
 	opts := newLoaderBatchedOptions(optsSetters...)
 
 	return &loaderBatched{
-		handler: handler,
+		handler: wrapLoaderBatchedHandler(handler, opts.middlewares),
 
 		inputCh: inputCh,
 
@@ -50,15 +50,37 @@ func (l *loaderBatched) Run(ctx context.Context) error {
 		return errors.Wrap(err, "failed to run batched loader preRunHooks")
 	}
 
+	if l.opts.partitionKey != nil {
+		return l.runPartitioned(ctx)
+	}
+
 	if l.opts.concurrency == 1 {
-		return l.runWorker(ctx)
+		return l.runWorker(ctx, l.inputCh)
 	}
 
 	g, ctx := errgroup.WithContext(ctx)
 
-	for i := 0; i <= l.opts.concurrency; i++ {
+	for i := 0; i < l.opts.concurrency; i++ {
 		g.Go(func() error {
-			return l.runWorker(ctx)
+			return l.runWorker(ctx, l.inputCh)
+		})
+	}
+
+	return g.Wait()
+}
+
+// runPartitioned dispatches messages with the same partition key to the same worker
+func (l *loaderBatched) runPartitioned(ctx context.Context) error {
+	partitionChs, inputChs := newPartitionChannels(l.opts.concurrency)
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return partitionMessages(ctx, l.inputCh, l.opts.partitionKey, partitionChs)
+	})
+	for _, inputCh := range inputChs {
+		inputCh := inputCh
+		g.Go(func() error {
+			return l.runWorker(ctx, inputCh)
 		})
 	}
 
@@ -89,14 +111,33 @@ func (l *loaderBatched) onCompleteHook(ctx context.Context, inMsgs []Message) er
 	return nil
 }
 
-func (l *loaderBatched) runWorker(ctx context.Context) error {
+func (l *loaderBatched) runWorker(ctx context.Context, inputCh <-chan Message) error {
 	var (
-		inMsgs []Message
-		opErr  error
-		err    error
+		inMsgs  []Message
+		hookCtx context.Context
+		opErr   error
+		err     error
+
+		deadLettered bool
 	)
 	for {
-		inMsgs, err = l.opts.batcher(ctx, l.inputCh)
+		err = callRecovering(func() error {
+			inMsgs, err = l.opts.batcher(ctx, inputCh)
+			return err
+		})
+		if panicErr, ok := err.(*PanicError); ok {
+			// messages collected by batcher are lost, so there's nothing to retry or acknowledge
+			err = l.onErrorHook(ctx, nil, panicErr)
+			if err != nil {
+				return errors.Wrap(err, "running batched loader on error hook has failed")
+			}
+
+			if l.opts.failOnErr {
+				return panicErr
+			}
+
+			continue
+		}
 		if err != nil {
 			return err
 		}
@@ -105,21 +146,48 @@ func (l *loaderBatched) runWorker(ctx context.Context) error {
 			return nil
 		}
 
-		opErr = l.handler(ctx, inMsgs)
+		hookCtx, opErr = l.opts.retryPolicy.run(ctx, func(ctx context.Context) error {
+			ctx, cancel := contextWithHandlerTimeout(ctx, l.opts.handlerTimeout)
+			defer cancel()
+
+			return callRecovering(func() error {
+				return l.handler(ctx, inMsgs)
+			}, inMsgs...)
+		})
 		if opErr != nil {
-			err = l.onErrorHook(ctx, inMsgs, opErr)
+			err = l.onErrorHook(hookCtx, inMsgs, opErr)
 			if err != nil {
 				return errors.Wrap(err, "running batched loader on error hook has failed")
 			}
 
+			deadLettered, err = sendToDeadLetterSink(hookCtx, l.opts.deadLetterSink, l.opts.name, inMsgs, opErr)
+			if err != nil {
+				return errors.Wrap(err, "failed to send batched loader messages to dead letter sink")
+			}
+
+			if !deadLettered {
+				err = nackMessages(ctx, inMsgs, opErr)
+				if err != nil {
+					return errors.Wrap(err, "failed to nack batched loader messages")
+				}
+			}
+
 			if l.opts.failOnErr {
 				return opErr
 			}
+
+			continue
 		}
 
-		err = l.onCompleteHook(ctx, inMsgs)
+		err = l.onCompleteHook(hookCtx, inMsgs)
 		if err != nil {
+			_ = nackMessages(ctx, inMsgs, err)
 			return errors.Wrap(err, "failed to run loader onComplete hook")
 		}
+
+		err = ackMessages(ctx, inMsgs)
+		if err != nil {
+			return errors.Wrap(err, "failed to ack batched loader messages")
+		}
 	}
 }
@@ -9,8 +9,16 @@ type loaderBatchedOptions struct {
 	hooksPreRun     []LoaderBatchedBatchedPreRunHook
 	hooksOnError    []LoaderBatchedOnErrorHook
 	hooksOnComplete []LoaderBatchedOnComplete
+	middlewares     []LoaderBatchedMiddleware
 	batcher         LoaderBatcher
 
+	retryPolicy    *RetryPolicy
+	deadLetterSink DeadLetterSink
+	handlerTimeout time.Duration
+
+	name         string
+	partitionKey PartitionKeyFunc
+
 	concurrency int
 
 	failOnErr bool
@@ -34,6 +42,11 @@ func newLoaderBatchedOptions(optsSetters ...LoaderBatchedOption) *loaderBatchedO
 
 type LoaderBatchedOption func(o *loaderBatchedOptions)
 
+// LoaderBatchedWithName sets name of the stage, used to describe it e.g. in dead letters
+func LoaderBatchedWithName(name string) LoaderBatchedOption {
+	return func(o *loaderBatchedOptions) { o.name = name }
+}
+
 type LoaderBatchedBatchedPreRunHook func(ctx context.Context, inputCh <-chan Message) error
 
 func LoaderBatchedWithPreRunHook(hook LoaderBatchedBatchedPreRunHook) LoaderBatchedOption {
@@ -52,15 +65,42 @@ func LoaderBatchedWithOnCompleteHook(hook LoaderBatchedOnComplete) LoaderBatched
 	return func(o *loaderBatchedOptions) { o.hooksOnComplete = append(o.hooksOnComplete, hook) }
 }
 
+// LoaderBatchedWithMiddleware wraps handler with a middleware. The first middleware is the outermost one.
+func LoaderBatchedWithMiddleware(middleware LoaderBatchedMiddleware) LoaderBatchedOption {
+	return func(o *loaderBatchedOptions) { o.middlewares = append(o.middlewares, middleware) }
+}
+
+// LoaderBatchedWithConcurrency sets number of workers. Values lower than 1 are treated as 1.
 func LoaderBatchedWithConcurrency(concurrency int) LoaderBatchedOption {
-	return func(o *loaderBatchedOptions) { o.concurrency = concurrency }
+	return func(o *loaderBatchedOptions) { o.concurrency = atLeastOne(concurrency) }
 }
 
 func LoaderBatchedWithFailOnError(failOnErr bool) LoaderBatchedOption {
 	return func(o *loaderBatchedOptions) { o.failOnErr = failOnErr }
 }
 
-type LoaderBatcher func(ctx context.Context, inMsgCh <-chan Message) ([]Message, error)
+// LoaderBatchedWithRetry retries failed handler calls according to the policy, before error hooks are called
+func LoaderBatchedWithRetry(policy RetryPolicy) LoaderBatchedOption {
+	return func(o *loaderBatchedOptions) { o.retryPolicy = &policy }
+}
+
+// LoaderBatchedWithDeadLetterSink routes messages that failed processing to the sink, after error hooks are called.
+// Every message of a failed batch is reported separately
+func LoaderBatchedWithDeadLetterSink(sink DeadLetterSink) LoaderBatchedOption {
+	return func(o *loaderBatchedOptions) { o.deadLetterSink = sink }
+}
+
+// LoaderBatchedWithHandlerTimeout limits duration of every handler call, by setting a deadline on its context. Handler
+// is expected to return once the context is done. Timed out calls are retried and reported as any other error
+func LoaderBatchedWithHandlerTimeout(timeout time.Duration) LoaderBatchedOption {
+	return func(o *loaderBatchedOptions) { o.handlerTimeout = timeout }
+}
+
+// LoaderBatchedWithPartitionKey dispatches messages with the same key to the same worker, so they are batched and
+// loaded sequentially in order, while messages with different keys are loaded concurrently
+func LoaderBatchedWithPartitionKey(keyFn PartitionKeyFunc) LoaderBatchedOption {
+	return func(o *loaderBatchedOptions) { o.partitionKey = keyFn }
+}
 
 func LoaderBatchedWithBatcher(batcher LoaderBatcher) LoaderBatchedOption {
 	return func(o *loaderBatchedOptions) {
@@ -69,191 +109,17 @@ func LoaderBatchedWithBatcher(batcher LoaderBatcher) LoaderBatchedOption {
 }
 
 func LoaderBatchedWithFixedSizeBatches(maxItems int) LoaderBatchedOption {
-	return LoaderBatchedWithBatcher(func(ctx context.Context, inMsgCh <-chan Message) ([]Message, error) {
-		var (
-			messages = make([]Message, 0, maxItems)
-			inMsg    Message
-			ok       bool
-		)
-		for {
-			if len(messages) >= maxItems {
-				return messages, nil
-			}
-
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case inMsg, ok = <-inMsgCh:
-				if !ok {
-					return messages, nil
-				}
-
-				messages = append(messages, inMsg)
-			}
-		}
-
-		return messages, nil
-	})
+	return LoaderBatchedWithBatcher(FixedSizeBatcher(maxItems))
 }
 
 func LoaderBatchedWithDrainedChannelBatches(maxItems int) LoaderBatchedOption {
-	return LoaderBatchedWithBatcher(func(ctx context.Context, inMsgCh <-chan Message) ([]Message, error) {
-		var (
-			messages = make([]Message, 0, maxItems)
-			inMsg    Message
-			ok       bool
-		)
-		for {
-			if len(messages) >= maxItems {
-				return messages, nil
-			}
-
-			if len(messages) == 0 {
-				select {
-				case <-ctx.Done():
-					return nil, ctx.Err()
-				case inMsg, ok = <-inMsgCh:
-					if !ok {
-						return messages, nil
-					}
-
-					messages = append(messages, inMsg)
-				}
-
-				continue
-			}
-
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case inMsg, ok = <-inMsgCh:
-				if !ok {
-					return messages, nil
-				}
-
-				messages = append(messages, inMsg)
-			default:
-				return messages, nil
-			}
-		}
-
-		return messages, nil
-	})
+	return LoaderBatchedWithBatcher(DrainedChannelBatcher(maxItems))
 }
 
 func LoaderBatchedWithThrottledBatches(interval time.Duration, maxItems int) LoaderBatchedOption {
-	return LoaderBatchedWithBatcher(func(ctx context.Context, inMsgCh <-chan Message) ([]Message, error) {
-		var (
-			messages = make([]Message, 0, maxItems)
-			inMsg    Message
-			ticker   *time.Timer
-			ok       bool
-		)
-		for {
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case inMsg, ok = <-inMsgCh:
-				if !ok {
-					return nil, nil
-				}
-
-				messages = append(messages, inMsg)
-
-				ticker = time.NewTimer(interval)
-
-				for {
-					if len(messages) >= maxItems {
-						return messages, nil
-					}
-
-					select {
-					case <-ctx.Done():
-						return nil, ctx.Err()
-					case inMsg, ok = <-inMsgCh:
-						if !ok {
-							// channel closed. There's not going to be any new messages. But return what we have buffered
-							return messages, nil
-						}
-
-						messages = append(messages, inMsg)
-					case <-ticker.C:
-						return messages, nil
-					}
-				}
-
-				return messages, nil
-			}
-		}
-	})
+	return LoaderBatchedWithBatcher(ThrottledBatcher(interval, maxItems))
 }
 
 func LoaderBatchedWithDebouncedBatches(interval time.Duration, maxItems int) LoaderBatchedOption {
-	return LoaderBatchedWithBatcher(func(ctx context.Context, inMsgCh <-chan Message) ([]Message, error) {
-		var (
-			messages = make([]Message, 0, maxItems)
-			inMsg    Message
-			ticker   *time.Timer
-			ok       bool
-		)
-		for {
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case inMsg, ok = <-inMsgCh:
-				if !ok {
-					return nil, nil
-				}
-
-				messages = append(messages, inMsg)
-
-				ticker = time.NewTimer(interval)
-
-				for {
-					if len(messages) >= maxItems {
-						return messages, nil
-					}
-
-					ticker.Reset(interval)
-
-					select {
-					case <-ctx.Done():
-						return nil, ctx.Err()
-					case inMsg, ok = <-inMsgCh:
-						if !ok {
-							// channel closed. There's not going to be any new messages. But return what we have buffered
-							return messages, nil
-						}
-
-						messages = append(messages, inMsg)
-					case <-ticker.C:
-						return messages, nil
-					}
-				}
-
-				return messages, nil
-			}
-		}
-	})
-}
-
-func defaultLoaderBatcher(ctx context.Context, inMsgCh <-chan Message) ([]Message, error) {
-	var (
-		inMsg Message
-		ok    bool
-	)
-	for {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case inMsg, ok = <-inMsgCh:
-			if !ok {
-				return nil, nil
-			}
-
-			return []Message{inMsg}, nil
-		}
-	}
-
-	return nil, nil
+	return LoaderBatchedWithBatcher(DebouncedBatcher(interval, maxItems))
 }
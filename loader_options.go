@@ -2,12 +2,21 @@ package etl
 
 import (
 	"context"
+	"time"
 )
 
 type loaderOptions struct {
 	hooksPreRun     []LoaderPreRunHook
 	hooksOnError    []LoaderOnErrorHook
 	hooksOnComplete []LoaderOnComplete
+	middlewares     []LoaderMiddleware
+
+	retryPolicy    *RetryPolicy
+	deadLetterSink DeadLetterSink
+	handlerTimeout time.Duration
+
+	name         string
+	partitionKey PartitionKeyFunc
 
 	concurrency int
 
@@ -32,6 +41,11 @@ func newLoaderOptions(optsSetters ...LoaderOption) *loaderOptions {
 
 type LoaderOption func(o *loaderOptions)
 
+// LoaderWithName sets name of the stage, used to describe it e.g. in dead letters
+func LoaderWithName(name string) LoaderOption {
+	return func(o *loaderOptions) { o.name = name }
+}
+
 type LoaderPreRunHook func(ctx context.Context, inputCh <-chan Message) error
 
 func LoaderWithPreRunHook(hook LoaderPreRunHook) LoaderOption {
@@ -50,10 +64,38 @@ func LoaderWithOnCompleteHook(hook LoaderOnComplete) LoaderOption {
 	return func(o *loaderOptions) { o.hooksOnComplete = append(o.hooksOnComplete, hook) }
 }
 
+// LoaderWithMiddleware wraps handler with a middleware. The first middleware is the outermost one.
+func LoaderWithMiddleware(middleware LoaderMiddleware) LoaderOption {
+	return func(o *loaderOptions) { o.middlewares = append(o.middlewares, middleware) }
+}
+
+// LoaderWithConcurrency sets number of workers. Values lower than 1 are treated as 1.
 func LoaderWithConcurrency(concurrency int) LoaderOption {
-	return func(o *loaderOptions) { o.concurrency = concurrency }
+	return func(o *loaderOptions) { o.concurrency = atLeastOne(concurrency) }
 }
 
 func LoaderWithFailOnError(failOnErr bool) LoaderOption {
 	return func(o *loaderOptions) { o.failOnErr = failOnErr }
 }
+
+// LoaderWithRetry retries failed handler calls according to the policy, before error hooks are called
+func LoaderWithRetry(policy RetryPolicy) LoaderOption {
+	return func(o *loaderOptions) { o.retryPolicy = &policy }
+}
+
+// LoaderWithDeadLetterSink routes messages that failed processing to the sink, after error hooks are called
+func LoaderWithDeadLetterSink(sink DeadLetterSink) LoaderOption {
+	return func(o *loaderOptions) { o.deadLetterSink = sink }
+}
+
+// LoaderWithHandlerTimeout limits duration of every handler call, by setting a deadline on its context. Handler
+// is expected to return once the context is done. Timed out calls are retried and reported as any other error
+func LoaderWithHandlerTimeout(timeout time.Duration) LoaderOption {
+	return func(o *loaderOptions) { o.handlerTimeout = timeout }
+}
+
+// LoaderWithPartitionKey dispatches messages with the same key to the same worker, so they are loaded sequentially
+// in order, while messages with different keys are loaded concurrently
+func LoaderWithPartitionKey(keyFn PartitionKeyFunc) LoaderOption {
+	return func(o *loaderOptions) { o.partitionKey = keyFn }
+}
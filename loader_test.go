@@ -17,6 +17,20 @@ func (f *fakeLoader) Handle(ctx context.Context, message etl.Message) error {
 	return nil
 }
 
+// runIntoLoader runs the runner together with a loader consuming its output channel, and returns loaded payloads
+func runIntoLoader(t *testing.T, runner etl.Runner, outputCh <-chan etl.Message) []interface{} {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l := &fakeLoader{}
+	loader := etl.NewLoader(outputCh, l.Handle)
+
+	err := etl.RunAll(ctx, runner, loader)
+	require.NoError(t, err)
+
+	return payloadsOf(l)
+}
+
 type fakeLoaderHook struct {
 	preRunCalls     []struct{}
 	onErrorCalls    []error
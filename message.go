@@ -1,18 +1,43 @@
 package etl
 
-import "time"
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
 
 type Message interface {
+	// ID uniquely identifies message
+	ID() string
 	Payload() interface{}
+	// Header returns value of the header, or empty string if it's not set
+	Header(key string) string
+	// Headers returns a copy of all message headers
+	Headers() map[string]string
 	CreatedAt() time.Time
 	ProcessingStartedAt() time.Time
+	// EventTime returns time when the event described by message happened, or time of message creation if it's not set
+	EventTime() time.Time
+	// Watermark returns time, up to which all events are expected to have been received by a stage, or zero time if
+	// it's unknown. Messages with event time before the watermark are late
+	Watermark() time.Time
+	// Ack acknowledges that message has been processed. Once all messages derived from a message sent by an extractor
+	// are acknowledged, extractor's OnAck hooks are called.
+	Ack(ctx context.Context) error
+	// Nack negatively acknowledges message, calling extractor's OnNack hooks right away.
+	Nack(ctx context.Context, err error) error
 }
 
 type message struct {
+	id      string
 	payload interface{}
+	headers map[string]string
 
 	createdAt           time.Time
 	processingStartedAt time.Time
+	eventTime           time.Time
+	watermark           time.Time
 }
 
 func NewMessage(payload interface{}, optsSetters ...MessageOption) Message {
@@ -24,13 +49,46 @@ func NewMessage(payload interface{}, optsSetters ...MessageOption) Message {
 
 	applyMessageOptions(msg, optsSetters...)
 
+	if msg.id == "" {
+		msg.id = newMessageID()
+	}
+
 	return msg
 }
 
+func newMessageID() string {
+	var id [16]byte
+
+	_, err := rand.Read(id[:])
+	if err != nil {
+		// crypto/rand never fails on supported platforms
+		panic(err)
+	}
+
+	return hex.EncodeToString(id[:])
+}
+
+func (m *message) ID() string {
+	return m.id
+}
+
 func (m *message) Payload() interface{} {
 	return m.payload
 }
 
+func (m *message) Header(key string) string {
+	return m.headers[key]
+}
+
+func (m *message) Headers() map[string]string {
+	headers := make(map[string]string, len(m.headers))
+	for key, value := range m.headers {
+		headers[key] = value
+	}
+
+	return headers
+}
+
 func (m *message) CreatedAt() time.Time {
 	return m.createdAt
 }
@@ -38,3 +96,25 @@ func (m *message) CreatedAt() time.Time {
 func (m *message) ProcessingStartedAt() time.Time {
 	return m.processingStartedAt
 }
+
+func (m *message) EventTime() time.Time {
+	if m.eventTime.IsZero() {
+		return m.createdAt
+	}
+
+	return m.eventTime
+}
+
+func (m *message) Watermark() time.Time {
+	return m.watermark
+}
+
+// Ack is a no-op, since message is not tracked. Tracking is attached by senders if necessary
+func (m *message) Ack(ctx context.Context) error {
+	return nil
+}
+
+// Nack is a no-op, since message is not tracked. Tracking is attached by senders if necessary
+func (m *message) Nack(ctx context.Context, err error) error {
+	return nil
+}
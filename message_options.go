@@ -12,8 +12,62 @@ func applyMessageOptions(msg *message, optsSetters ...MessageOption) {
 
 type MessageOption func(o *message)
 
+// messageWithCreatedAt overrides time of message creation, e.g. to restore a message read from a file
+func messageWithCreatedAt(tm time.Time) MessageOption {
+	return func(o *message) {
+		o.createdAt = tm
+	}
+}
+
 func MessageWithProcessingStartedAt(tm time.Time) MessageOption {
 	return func(o *message) {
 		o.processingStartedAt = tm
 	}
 }
+
+// MessageWithEventTime sets time when the event described by message happened
+func MessageWithEventTime(tm time.Time) MessageOption {
+	return func(o *message) {
+		o.eventTime = tm
+	}
+}
+
+// MessageWithWatermark sets watermark carried by the message
+func MessageWithWatermark(tm time.Time) MessageOption {
+	return func(o *message) {
+		o.watermark = tm
+	}
+}
+
+func MessageWithID(id string) MessageOption {
+	return func(o *message) {
+		o.id = id
+	}
+}
+
+func MessageWithHeader(key, value string) MessageOption {
+	return func(o *message) {
+		if o.headers == nil {
+			o.headers = make(map[string]string)
+		}
+
+		o.headers[key] = value
+	}
+}
+
+// MessageWithHeaders copies all headers into the message, overriding existing values
+func MessageWithHeaders(headers map[string]string) MessageOption {
+	return func(o *message) {
+		if len(headers) == 0 {
+			return
+		}
+
+		if o.headers == nil {
+			o.headers = make(map[string]string, len(headers))
+		}
+
+		for key, value := range headers {
+			o.headers[key] = value
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package etl_test
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMessage_GeneratesUniqueID(t *testing.T) {
+	msg1 := etl.NewMessage(1)
+	msg2 := etl.NewMessage(1)
+
+	require.NotEmpty(t, msg1.ID())
+	require.NotEqual(t, msg1.ID(), msg2.ID())
+}
+
+func TestMessage_WithIDAndHeaders(t *testing.T) {
+	msg := etl.NewMessage(1,
+		etl.MessageWithID("id"),
+		etl.MessageWithHeaders(map[string]string{"tenant": "a", "partition": "1"}),
+		etl.MessageWithHeader("tenant", "b"),
+	)
+
+	require.Equal(t, "id", msg.ID())
+	require.Equal(t, "b", msg.Header("tenant"))
+	require.Equal(t, "", msg.Header("offset"))
+	require.Equal(t, map[string]string{"tenant": "b", "partition": "1"}, msg.Headers())
+
+	msg.Headers()["tenant"] = "c"
+	require.Equal(t, "b", msg.Header("tenant"), "expected headers to be copied")
+}
+
+func TestMessage_TransformerPropagatesHeaders(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var inMsg etl.Message
+
+	extractor := etl.NewExtractor(func(ctx context.Context, sender etl.Sender) error {
+		inMsg = etl.NewMessage(1, etl.MessageWithHeader("trace", "abc"))
+		return sender.SendMessage(ctx, inMsg)
+	})
+	transformer := etl.NewTransformer(extractor.OutputCh(), fakeTransformer)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(transformer.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor, transformer, loader)
+	require.NoError(t, err)
+
+	require.Equal(t, 1, len(l.calls))
+	require.Equal(t, 2, l.calls[0].Payload())
+	require.Equal(t, "abc", l.calls[0].Header("trace"))
+	require.Equal(t, inMsg.ProcessingStartedAt(), l.calls[0].ProcessingStartedAt())
+	require.NotEqual(t, inMsg.ID(), l.calls[0].ID())
+}
+
+func TestMessage_OutgoingHeadersFromContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	extractor := etl.NewExtractor(func(ctx context.Context, sender etl.Sender) error {
+		ctx = etl.ContextWithOutgoingHeaders(ctx, map[string]string{"trace": "abc", "tenant": "a"})
+		ctx = etl.ContextWithOutgoingHeaders(ctx, map[string]string{"trace": "def"})
+
+		return sender.SendMessage(ctx, etl.NewMessage(1, etl.MessageWithHeaders(map[string]string{"trace": "xyz", "partition": "1"})))
+	})
+	l := &fakeLoader{}
+	loader := etl.NewLoader(extractor.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.NoError(t, err)
+
+	require.Equal(t, 1, len(l.calls))
+	require.Equal(t, "def", l.calls[0].Header("trace"))
+	require.Equal(t, map[string]string{"trace": "def", "tenant": "a", "partition": "1"}, l.calls[0].Headers())
+}
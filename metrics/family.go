@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+)
+
+type series struct {
+	value float64
+
+	bucketCounts []uint64
+	sum          float64
+	count        uint64
+}
+
+// family is a metric with a single stage label, exposed in Prometheus text format
+type family struct {
+	name    string
+	help    string
+	kind    string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+func newFamily(name, help, kind string, buckets []float64) *family {
+	return &family{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+}
+
+// get returns series of the stage. Must be called with mutex locked
+func (f *family) get(stage string) *series {
+	s, ok := f.series[stage]
+	if !ok {
+		s = &series{bucketCounts: make([]uint64, len(f.buckets))}
+		f.series[stage] = s
+	}
+
+	return s
+}
+
+// add increases counter or gauge of the stage by v
+func (f *family) add(stage string, v float64) {
+	f.mu.Lock()
+	f.get(stage).value += v
+	f.mu.Unlock()
+}
+
+// observe records v in histogram of the stage
+func (f *family) observe(stage string, v float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := f.get(stage)
+	for i, bound := range f.buckets {
+		if v <= bound {
+			s.bucketCounts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (f *family) write(w io.Writer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.series) == 0 {
+		return nil
+	}
+
+	stages := make([]string, 0, len(f.series))
+	for stage := range f.series {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+	if err != nil {
+		return err
+	}
+
+	for _, stage := range stages {
+		err = f.writeSeries(w, stage, f.series[stage])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *family) writeSeries(w io.Writer, stage string, s *series) error {
+	label := `stage="` + escapeLabelValue(stage) + `"`
+
+	if f.kind != kindHistogram {
+		_, err := fmt.Fprintf(w, "%s{%s} %s\n", f.name, label, formatFloat(s.value))
+		return err
+	}
+
+	for i, bound := range f.buckets {
+		_, err := fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", f.name, label, formatFloat(bound), s.bucketCounts[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(
+		w,
+		"%s_bucket{%s,le=\"+Inf\"} %d\n%s_sum{%s} %s\n%s_count{%s} %d\n",
+		f.name, label, s.count,
+		f.name, label, formatFloat(s.sum),
+		f.name, label, s.count,
+	)
+
+	return err
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,222 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"github.com/damian-szulc/go-etl"
+	"github.com/damian-szulc/go-etl/metrics"
+	"github.com/damian-szulc/go-etl/queue"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistry_InstrumentsPipeline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registry := metrics.NewRegistry(metrics.WithLatencyBuckets(1), metrics.WithBatchSizeBuckets(2, 10))
+
+	extractor := etl.NewExtractor(func(ctx context.Context, sender etl.Sender) error {
+		for i := 1; i <= 5; i++ {
+			err := sender.Send(ctx, i)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, registry.Extractor("extract"))
+
+	errOdd := errors.New("odd")
+	transformer := etl.NewTransformer(extractor.OutputCh(), func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+		if inMsg.Payload().(int)%2 == 1 {
+			return errOdd
+		}
+
+		return sender.Send(ctx, inMsg.Payload())
+	},
+		registry.Transformer("filter"),
+		etl.TransformerWithFailOnError(false),
+		etl.TransformerWithRetry(etl.RetryPolicy{MaxAttempts: 2}),
+	)
+
+	loader := etl.NewLoaderBatched(transformer.OutputCh(), func(ctx context.Context, messages []etl.Message) error {
+		return nil
+	},
+		registry.LoaderBatched("store"),
+		etl.LoaderBatchedWithFixedSizeBatches(10),
+	)
+
+	err := etl.RunAll(ctx, extractor, transformer, loader)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(registry.Handler())
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+
+	for _, line := range []string{
+		"# TYPE etl_messages_in_total counter",
+		`etl_messages_in_total{stage="filter"} 5`,
+		`etl_messages_in_total{stage="store"} 2`,
+		`etl_messages_out_total{stage="extract"} 5`,
+		`etl_messages_out_total{stage="filter"} 2`,
+		// every odd message fails twice
+		`etl_errors_total{stage="filter"} 6`,
+		"# TYPE etl_handler_duration_seconds histogram",
+		`etl_handler_duration_seconds_bucket{stage="filter",le="1"} 8`,
+		`etl_handler_duration_seconds_bucket{stage="filter",le="+Inf"} 8`,
+		`etl_handler_duration_seconds_count{stage="filter"} 8`,
+		`etl_batch_size_bucket{stage="store",le="2"} 1`,
+		`etl_batch_size_sum{stage="store"} 2`,
+		`etl_workers_in_flight{stage="filter"} 0`,
+	} {
+		require.Contains(t, string(body), line+"\n")
+	}
+}
+
+func TestRegistry_CountsMessagesSentBySuccessfulAttempt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registry := metrics.NewRegistry()
+
+	errFlaky := errors.New("flaky")
+	transformer := etl.NewTransformer(newFilledChannel(1), func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+		err := sender.Send(ctx, inMsg.Payload())
+		if err != nil {
+			return err
+		}
+
+		// output of failed attempts is discarded
+		if etl.AttemptFromContext(ctx) < 3 {
+			return errFlaky
+		}
+
+		return nil
+	},
+		registry.Transformer("flaky"),
+		etl.TransformerWithRetry(etl.RetryPolicy{MaxAttempts: 3}),
+	)
+
+	var loaded int
+	loader := etl.NewLoader(transformer.OutputCh(), func(ctx context.Context, message etl.Message) error {
+		loaded++
+		return nil
+	})
+
+	err := etl.RunAll(ctx, transformer, loader)
+	require.NoError(t, err)
+	require.Equal(t, 1, loaded)
+
+	body := scrape(t, registry)
+	require.Contains(t, body, `etl_messages_in_total{stage="flaky"} 1`+"\n")
+	require.Contains(t, body, `etl_messages_out_total{stage="flaky"} 1`+"\n")
+	require.Contains(t, body, `etl_errors_total{stage="flaky"} 2`+"\n")
+}
+
+func TestRegistry_InstrumentsTransformerBatched(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registry := metrics.NewRegistry(metrics.WithBatchSizeBuckets(2, 10))
+
+	transformer := etl.NewTransformerBatched(newFilledChannel(1, 2, 3), func(ctx context.Context, inMsgs []etl.Message, sender etl.Sender) error {
+		return sender.Send(ctx, len(inMsgs))
+	},
+		registry.TransformerBatched("batch"),
+		etl.TransformerBatchedWithFixedSizeBatches(2),
+	)
+	loader := etl.NewLoader(transformer.OutputCh(), func(ctx context.Context, message etl.Message) error {
+		return nil
+	})
+
+	err := etl.RunAll(ctx, transformer, loader)
+	require.NoError(t, err)
+
+	body := scrape(t, registry)
+	for _, line := range []string{
+		`etl_messages_in_total{stage="batch"} 3`,
+		`etl_messages_out_total{stage="batch"} 2`,
+		`etl_batch_size_bucket{stage="batch",le="2"} 2`,
+		`etl_batch_size_sum{stage="batch"} 3`,
+		`etl_handler_duration_seconds_count{stage="batch"} 2`,
+	} {
+		require.Contains(t, body, line+"\n")
+	}
+}
+
+func TestRegistry_InstrumentsQueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registry := metrics.NewRegistry()
+
+	q := queue.New(newFilledChannel(1, 2, 3), queue.WithDriver(queue.NewDriverDefault(
+		queue.DefaultDriverWithEnqueueHook(registry.QueueOnEnqueueHook("buffer")),
+		queue.DefaultDriverWithDequeueHook(registry.QueueOnDequeueHook("buffer")),
+	)))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- q.Run(ctx)
+	}()
+
+	// queue keeps waiting for new messages, until it's cancelled
+	for i := 0; i < 3; i++ {
+		<-q.OutputCh()
+	}
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(scrape(t, registry), `etl_messages_out_total{stage="buffer"} 3`+"\n")
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.Equal(t, context.Canceled, <-errCh)
+
+	body := scrape(t, registry)
+	require.Contains(t, body, `etl_messages_in_total{stage="buffer"} 3`+"\n")
+	require.Contains(t, body, `etl_queue_depth{stage="buffer"} 0`+"\n")
+}
+
+func TestRegistry_EscapesStageNames(t *testing.T) {
+	registry := metrics.NewRegistry(metrics.WithNamespace("app"))
+
+	loader := etl.NewLoader(newFilledChannel(1), func(ctx context.Context, message etl.Message) error {
+		return nil
+	}, registry.Loader("say \"hi\"\n"))
+
+	err := loader.Run(context.Background())
+	require.NoError(t, err)
+
+	require.Contains(t, scrape(t, registry), `app_messages_in_total{stage="say \"hi\"\n"} 1`)
+}
+
+func scrape(t *testing.T, registry *metrics.Registry) string {
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	return recorder.Body.String()
+}
+
+func newFilledChannel(payload ...interface{}) <-chan etl.Message {
+	ch := make(chan etl.Message, len(payload))
+	for _, p := range payload {
+		ch <- etl.NewMessage(p)
+	}
+	close(ch)
+
+	return ch
+}
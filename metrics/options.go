@@ -0,0 +1,40 @@
+package metrics
+
+type options struct {
+	namespace        string
+	latencyBuckets   []float64
+	batchSizeBuckets []float64
+}
+
+func newOptions(optsSetters ...Option) *options {
+	opts := &options{
+		namespace:        "etl",
+		latencyBuckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		batchSizeBuckets: []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000},
+	}
+
+	for _, setter := range optsSetters {
+		if setter != nil {
+			setter(opts)
+		}
+	}
+
+	return opts
+}
+
+type Option func(o *options)
+
+// WithNamespace sets prefix of metric names, "etl" by default
+func WithNamespace(namespace string) Option {
+	return func(o *options) { o.namespace = namespace }
+}
+
+// WithLatencyBuckets sets upper bounds of handler duration histogram buckets, in seconds. Bounds must be sorted.
+func WithLatencyBuckets(buckets ...float64) Option {
+	return func(o *options) { o.latencyBuckets = buckets }
+}
+
+// WithBatchSizeBuckets sets upper bounds of batch size histogram buckets. Bounds must be sorted.
+func WithBatchSizeBuckets(buckets ...float64) Option {
+	return func(o *options) { o.batchSizeBuckets = buckets }
+}
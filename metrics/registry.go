@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// Registry collects metrics of instrumented stages, labelled by stage name, and exposes them in Prometheus text format
+type Registry struct {
+	messagesIn      *family
+	messagesOut     *family
+	errors          *family
+	handlerDuration *family
+	batchSize       *family
+	workersInFlight *family
+	queueDepth      *family
+
+	families []*family
+}
+
+func NewRegistry(optsSetters ...Option) *Registry {
+	opts := newOptions(optsSetters...)
+
+	r := &Registry{
+		messagesIn:      newFamily(opts.namespace+"_messages_in_total", "Number of messages received by a stage.", kindCounter, nil),
+		messagesOut:     newFamily(opts.namespace+"_messages_out_total", "Number of messages sent by a stage.", kindCounter, nil),
+		errors:          newFamily(opts.namespace+"_errors_total", "Number of failed handler calls of a stage, including retried ones.", kindCounter, nil),
+		handlerDuration: newFamily(opts.namespace+"_handler_duration_seconds", "Duration of handler calls of a stage.", kindHistogram, opts.latencyBuckets),
+		batchSize:       newFamily(opts.namespace+"_batch_size", "Number of messages in batches of a stage.", kindHistogram, opts.batchSizeBuckets),
+		workersInFlight: newFamily(opts.namespace+"_workers_in_flight", "Number of handler calls of a stage in progress.", kindGauge, nil),
+		queueDepth:      newFamily(opts.namespace+"_queue_depth", "Number of messages waiting in a queue.", kindGauge, nil),
+	}
+	r.families = []*family{
+		r.messagesIn,
+		r.messagesOut,
+		r.errors,
+		r.handlerDuration,
+		r.batchSize,
+		r.workersInFlight,
+		r.queueDepth,
+	}
+
+	return r
+}
+
+// Write writes all collected metrics in Prometheus text format
+func (r *Registry) Write(w io.Writer) error {
+	var err error
+	for _, f := range r.families {
+		err = f.write(w)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Handler returns http handler exposing collected metrics in Prometheus text format
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		var buf bytes.Buffer
+
+		err := r.Write(&buf)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write(buf.Bytes())
+	})
+}
@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+	"github.com/damian-szulc/go-etl/queue"
+	"time"
+)
+
+// Extractor instruments extractor with number of sent messages, handler errors and running handlers
+func (r *Registry) Extractor(stage string) etl.ExtractorOption {
+	return etl.ExtractorWithMiddleware(func(next etl.ExtractorHandler) etl.ExtractorHandler {
+		return func(ctx context.Context, sender etl.Sender) error {
+			r.workersInFlight.add(stage, 1)
+			defer r.workersInFlight.add(stage, -1)
+
+			err := next(ctx, r.countingSender(stage, sender))
+			if err != nil {
+				r.errors.add(stage, 1)
+			}
+
+			return err
+		}
+	})
+}
+
+// Transformer instruments transformer with number of received and sent messages, handler errors, handler duration
+// and running handlers. Retried messages are received once, and only messages sent by the successful attempt are
+// counted as sent.
+func (r *Registry) Transformer(stage string) etl.TransformerOption {
+	return etl.TransformerWithOptions(
+		etl.TransformerWithMiddleware(func(next etl.TransformerHandler) etl.TransformerHandler {
+			return func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+				if etl.AttemptFromContext(ctx) == 1 {
+					r.messagesIn.add(stage, 1)
+				}
+
+				return r.handle(stage, func() error {
+					return next(ctx, inMsg, sender)
+				})
+			}
+		}),
+		// hooks are called for messages actually sent, once output of the successful attempt is released
+		etl.TransformerWithOnCompleteHook(func(ctx context.Context, inMsg etl.Message, outMsg etl.Message, chNr uint) error {
+			r.messagesOut.add(stage, 1)
+			return nil
+		}),
+	)
+}
+
+// TransformerBatched instruments batched transformer with number of received and sent messages, batch sizes, handler
+// errors, handler duration and running handlers. Retried batches are received once, and only messages sent by the
+// successful attempt are counted as sent.
+func (r *Registry) TransformerBatched(stage string) etl.TransformerBatchedOption {
+	return etl.TransformerBatchedWithOptions(
+		etl.TransformerBatchedWithMiddleware(func(next etl.TransformerBatchedHandler) etl.TransformerBatchedHandler {
+			return func(ctx context.Context, inMsgs []etl.Message, sender etl.Sender) error {
+				if etl.AttemptFromContext(ctx) == 1 {
+					r.messagesIn.add(stage, float64(len(inMsgs)))
+					r.batchSize.observe(stage, float64(len(inMsgs)))
+				}
+
+				return r.handle(stage, func() error {
+					return next(ctx, inMsgs, sender)
+				})
+			}
+		}),
+		etl.TransformerBatchedWithOnCompleteHook(func(ctx context.Context, inMsgs []etl.Message, outMsg etl.Message) error {
+			r.messagesOut.add(stage, 1)
+			return nil
+		}),
+	)
+}
+
+// Loader instruments loader with number of received messages, handler errors, handler duration and running handlers.
+// Retried messages are received once.
+func (r *Registry) Loader(stage string) etl.LoaderOption {
+	return etl.LoaderWithMiddleware(func(next etl.LoaderHandler) etl.LoaderHandler {
+		return func(ctx context.Context, message etl.Message) error {
+			if etl.AttemptFromContext(ctx) == 1 {
+				r.messagesIn.add(stage, 1)
+			}
+
+			return r.handle(stage, func() error {
+				return next(ctx, message)
+			})
+		}
+	})
+}
+
+// LoaderBatched instruments batched loader with number of received messages, batch sizes, handler errors, handler
+// duration and running handlers. Retried batches are received once.
+func (r *Registry) LoaderBatched(stage string) etl.LoaderBatchedOption {
+	return etl.LoaderBatchedWithMiddleware(func(next etl.LoaderBatchedHandler) etl.LoaderBatchedHandler {
+		return func(ctx context.Context, messages []etl.Message) error {
+			if etl.AttemptFromContext(ctx) == 1 {
+				r.messagesIn.add(stage, float64(len(messages)))
+				r.batchSize.observe(stage, float64(len(messages)))
+			}
+
+			return r.handle(stage, func() error {
+				return next(ctx, messages)
+			})
+		}
+	})
+}
+
+// QueueOnEnqueueHook instruments queue driver with number of received messages and queue depth, e.g.
+// queue.DefaultDriverWithEnqueueHook(registry.QueueOnEnqueueHook("queue"))
+func (r *Registry) QueueOnEnqueueHook(stage string) queue.OnEnqueueHook {
+	return func(ctx context.Context, size int) error {
+		// hooks of enqueued and dequeued messages might be called concurrently, so depth is tracked incrementally
+		r.messagesIn.add(stage, 1)
+		r.queueDepth.add(stage, 1)
+
+		return nil
+	}
+}
+
+// QueueOnDequeueHook instruments queue driver with number of sent messages and queue depth, e.g.
+// queue.DefaultDriverWithDequeueHook(registry.QueueOnDequeueHook("queue"))
+func (r *Registry) QueueOnDequeueHook(stage string) queue.OnDequeueHook {
+	return func(ctx context.Context, size int) error {
+		r.messagesOut.add(stage, 1)
+		r.queueDepth.add(stage, -1)
+
+		return nil
+	}
+}
+
+// handle calls a single handler attempt, recording its duration and result
+func (r *Registry) handle(stage string, fn func() error) error {
+	r.workersInFlight.add(stage, 1)
+	defer r.workersInFlight.add(stage, -1)
+
+	startedAt := time.Now()
+	err := fn()
+	r.handlerDuration.observe(stage, time.Since(startedAt).Seconds())
+
+	if err != nil {
+		r.errors.add(stage, 1)
+	}
+
+	return err
+}
+
+func (r *Registry) countingSender(stage string, sender etl.Sender) etl.Sender {
+	return &countingSender{
+		sender: sender,
+		onSent: func() {
+			r.messagesOut.add(stage, 1)
+		},
+	}
+}
+
+// countingSender counts messages successfully sent by a handler
+type countingSender struct {
+	sender etl.Sender
+	onSent func()
+}
+
+func (s *countingSender) sent(err error) error {
+	if err == nil {
+		s.onSent()
+	}
+
+	return err
+}
+
+func (s *countingSender) Send(ctx context.Context, payload interface{}) error {
+	return s.sent(s.sender.Send(ctx, payload))
+}
+
+func (s *countingSender) SendCh(ctx context.Context, outChNr uint, payload interface{}) error {
+	return s.sent(s.sender.SendCh(ctx, outChNr, payload))
+}
+
+func (s *countingSender) SendMessage(ctx context.Context, message etl.Message) error {
+	return s.sent(s.sender.SendMessage(ctx, message))
+}
+
+func (s *countingSender) SendChMessage(ctx context.Context, outChNr uint, message etl.Message) error {
+	return s.sent(s.sender.SendChMessage(ctx, outChNr, message))
+}
@@ -0,0 +1,59 @@
+package etl
+
+// ExtractorMiddleware wraps extractor handler, e.g. to instrument it
+type ExtractorMiddleware func(next ExtractorHandler) ExtractorHandler
+
+// TransformerMiddleware wraps transformer handler, e.g. to instrument it. Wrapped handler is called for every attempt.
+type TransformerMiddleware func(next TransformerHandler) TransformerHandler
+
+// TransformerBatchedMiddleware wraps batched transformer handler, e.g. to instrument it. Wrapped handler is called for
+// every attempt.
+type TransformerBatchedMiddleware func(next TransformerBatchedHandler) TransformerBatchedHandler
+
+// LoaderMiddleware wraps loader handler, e.g. to instrument it. Wrapped handler is called for every attempt.
+type LoaderMiddleware func(next LoaderHandler) LoaderHandler
+
+// LoaderBatchedMiddleware wraps batched loader handler, e.g. to instrument it. Wrapped handler is called for every
+// attempt.
+type LoaderBatchedMiddleware func(next LoaderBatchedHandler) LoaderBatchedHandler
+
+// wrapExtractorHandler applies middlewares in reverse, so the first one is the outermost
+func wrapExtractorHandler(handler ExtractorHandler, middlewares []ExtractorMiddleware) ExtractorHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+func wrapTransformerHandler(handler TransformerHandler, middlewares []TransformerMiddleware) TransformerHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+func wrapTransformerBatchedHandler(handler TransformerBatchedHandler, middlewares []TransformerBatchedMiddleware) TransformerBatchedHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+func wrapLoaderHandler(handler LoaderHandler, middlewares []LoaderMiddleware) LoaderHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+func wrapLoaderBatchedHandler(handler LoaderBatchedHandler, middlewares []LoaderBatchedMiddleware) LoaderBatchedHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
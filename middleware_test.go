@@ -0,0 +1,90 @@
+package etl_test
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLoader_MiddlewaresWrapHandlerInOrder(t *testing.T) {
+	var calls []string
+	middleware := func(name string) etl.LoaderMiddleware {
+		return func(next etl.LoaderHandler) etl.LoaderHandler {
+			return func(ctx context.Context, message etl.Message) error {
+				calls = append(calls, name+" before")
+				err := next(ctx, message)
+				calls = append(calls, name+" after")
+
+				return err
+			}
+		}
+	}
+
+	loader := etl.NewLoader(
+		newFilledChannel(1),
+		func(ctx context.Context, message etl.Message) error {
+			calls = append(calls, "handler")
+			return nil
+		},
+		etl.LoaderWithMiddleware(middleware("outer")),
+		etl.LoaderWithMiddleware(middleware("inner")),
+	)
+
+	err := loader.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"outer before", "inner before", "handler", "inner after", "outer after"}, calls)
+}
+
+func TestTransformer_MiddlewareWrapsSender(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transformer := etl.NewTransformer(
+		newFilledChannel(1, 2),
+		func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+			return sender.Send(ctx, inMsg.Payload())
+		},
+		etl.TransformerWithMiddleware(func(next etl.TransformerHandler) etl.TransformerHandler {
+			return func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+				return next(ctx, inMsg, doublingSender{sender})
+			}
+		}),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(transformer.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, transformer, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{2, 4}, payloadsOf(l))
+}
+
+type doublingSender struct {
+	etl.Sender
+}
+
+func (s doublingSender) Send(ctx context.Context, payload interface{}) error {
+	return s.Sender.Send(ctx, payload.(int)*2)
+}
+
+func TestTransformerBatched_MiddlewareWrapsSender(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transformer := etl.NewTransformerBatched(
+		newFilledChannel(1, 2, 3),
+		fakeSumTransformer,
+		etl.TransformerBatchedWithFixedSizeBatches(2),
+		etl.TransformerBatchedWithMiddleware(func(next etl.TransformerBatchedHandler) etl.TransformerBatchedHandler {
+			return func(ctx context.Context, inMsgs []etl.Message, sender etl.Sender) error {
+				return next(ctx, inMsgs, doublingSender{sender})
+			}
+		}),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(transformer.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, transformer, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{6, 6}, payloadsOf(l))
+}
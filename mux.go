@@ -0,0 +1,241 @@
+package etl
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"reflect"
+	"time"
+)
+
+// Mux merges multiple input channels into a single output channel. Output channel is closed once all input channels
+// are closed. Messages are sent with the lowest watermark of inputs, which are still open.
+type Mux interface {
+	Runner
+	OutputCh() <-chan Message
+}
+
+type mux struct {
+	inputChs []<-chan Message
+	outputCh chan Message
+
+	watermarks []time.Time
+	closed     []bool
+
+	opts *muxOptions
+}
+
+func NewMux(inputChs []<-chan Message, optsSetters ...MuxOption) Mux {
+	opts := newMuxOptions(optsSetters...)
+
+	return &mux{
+		inputChs: inputChs,
+		outputCh: make(chan Message, opts.outputChannelBufferSize),
+
+		opts: opts,
+	}
+}
+
+func (m *mux) OutputCh() <-chan Message {
+	return m.outputCh
+}
+
+func (m *mux) preRunHooks(ctx context.Context) error {
+	var err error
+	for _, hook := range m.opts.hooksPreRun {
+		err = hook(ctx, m.inputChs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *mux) weight(i int) int {
+	if i >= len(m.opts.weights) || m.opts.weights[i] < 1 {
+		return 1
+	}
+
+	return m.opts.weights[i]
+}
+
+// observe advances watermark of the input, which has received the message
+func (m *mux) observe(i int, msg Message) {
+	if msg.Watermark().After(m.watermarks[i]) {
+		m.watermarks[i] = msg.Watermark()
+	}
+}
+
+// watermark returns the lowest watermark of open inputs
+func (m *mux) watermark() time.Time {
+	var (
+		watermark time.Time
+		found     bool
+	)
+	for i, closed := range m.closed {
+		if closed {
+			continue
+		}
+
+		if !found || m.watermarks[i].Before(watermark) {
+			watermark = m.watermarks[i]
+			found = true
+		}
+	}
+
+	return watermark
+}
+
+func (m *mux) send(ctx context.Context, msg Message) error {
+	msg = withExactWatermark(msg, m.watermark())
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case m.outputCh <- msg:
+	}
+
+	return nil
+}
+
+// Run merges messages, until all input channels are closed. Note that execution of this function is blocking, until processing is finished.
+func (m *mux) Run(ctx context.Context) error {
+	err := m.preRunHooks(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to run mux preRunHooks")
+	}
+
+	defer close(m.outputCh)
+
+	m.watermarks = make([]time.Time, len(m.inputChs))
+	m.closed = make([]bool, len(m.inputChs))
+
+	if m.opts.less != nil {
+		return m.runOrdered(ctx)
+	}
+
+	var (
+		open      = make([]int, 0, len(m.inputChs))
+		stillOpen []int
+		received  bool
+		pos       int
+		msg       Message
+		ok        bool
+	)
+	for i := range m.inputChs {
+		open = append(open, i)
+	}
+
+	for len(open) > 0 {
+		// read available messages from every input in turn, up to its weight
+		received = false
+		stillOpen = open[:0]
+		for _, i := range open {
+			ok = true
+
+		readInput:
+			for n := 0; n < m.weight(i); n++ {
+				select {
+				case msg, ok = <-m.inputChs[i]:
+					if !ok {
+						m.closed[i] = true
+						break readInput
+					}
+
+					m.observe(i, msg)
+					err = m.send(ctx, msg)
+					if err != nil {
+						return err
+					}
+
+					received = true
+				default:
+					break readInput
+				}
+			}
+
+			if ok {
+				stillOpen = append(stillOpen, i)
+			}
+		}
+		open = stillOpen
+
+		if received || len(open) == 0 {
+			continue
+		}
+
+		// none of inputs has a message available, so wait for any of them
+		pos, msg, ok, err = m.wait(ctx, open)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			m.closed[open[pos]] = true
+			open = append(open[:pos], open[pos+1:]...)
+			continue
+		}
+
+		m.observe(open[pos], msg)
+		err = m.send(ctx, msg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runOrdered performs k-way merge of sorted input channels
+func (m *mux) runOrdered(ctx context.Context) error {
+	sources := make([]mergeSource, len(m.inputChs))
+	for i, inputCh := range m.inputChs {
+		i, inputCh := i, inputCh
+		sources[i] = func() (interface{}, bool, error) {
+			select {
+			case <-ctx.Done():
+				return nil, false, ctx.Err()
+			case msg, ok := <-inputCh:
+				if !ok {
+					m.closed[i] = true
+					return nil, false, nil
+				}
+
+				m.observe(i, msg)
+				return msg, true, nil
+			}
+		}
+	}
+
+	return mergeSorted(
+		sources,
+		func(a, b interface{}) bool {
+			return m.opts.less(a.(Message), b.(Message))
+		},
+		func(v interface{}) error {
+			return m.send(ctx, v.(Message))
+		},
+	)
+}
+
+// wait blocks until any of open inputs receives a message or is closed. Returns position of the input in open slice
+func (m *mux) wait(ctx context.Context, open []int) (int, Message, bool, error) {
+	cases := make([]reflect.SelectCase, 0, len(open)+1)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	for _, i := range open {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(m.inputChs[i])})
+	}
+
+	chosen, value, ok := reflect.Select(cases)
+	if chosen == 0 {
+		return 0, nil, false, ctx.Err()
+	}
+
+	if !ok {
+		return chosen - 1, nil, false, nil
+	}
+
+	msg, _ := value.Interface().(Message)
+
+	return chosen - 1, msg, true, nil
+}
@@ -0,0 +1,48 @@
+package etl
+
+import "context"
+
+type muxOptions struct {
+	hooksPreRun []MuxPreRunHook
+
+	weights                 []int
+	less                    MessageLessFunc
+	outputChannelBufferSize int
+}
+
+func newMuxOptions(optsSetters ...MuxOption) *muxOptions {
+	opts := &muxOptions{}
+
+	for _, setter := range optsSetters {
+		if setter != nil {
+			setter(opts)
+		}
+	}
+
+	return opts
+}
+
+type MuxOption func(o *muxOptions)
+
+type MuxPreRunHook func(ctx context.Context, inputChs []<-chan Message) error
+
+func MuxWithPreRunHook(hook MuxPreRunHook) MuxOption {
+	return func(o *muxOptions) { o.hooksPreRun = append(o.hooksPreRun, hook) }
+}
+
+func MuxWithOutputChannelBufferSize(size int) MuxOption {
+	return func(o *muxOptions) { o.outputChannelBufferSize = size }
+}
+
+// MuxWithWeights sets how many messages are read from every input channel in a single round, if they are available.
+// By default, one message is read from each input channel in turn. Weights lower than 1 are treated as 1.
+func MuxWithWeights(weights ...int) MuxOption {
+	return func(o *muxOptions) { o.weights = weights }
+}
+
+// MuxWithOrder merges input channels, which are already sorted according to less, into a single sorted output channel.
+// A message is sent only once every open input channel has a message available, so slow inputs block the merge.
+// Messages considered equal are sent in order of input channels. Weights are ignored.
+func MuxWithOrder(less MessageLessFunc) MuxOption {
+	return func(o *muxOptions) { o.less = less }
+}
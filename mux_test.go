@@ -0,0 +1,249 @@
+package etl_test
+
+import (
+	"context"
+	"errors"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newFilledChannel(payload ...interface{}) <-chan etl.Message {
+	ch := make(chan etl.Message, len(payload))
+	for _, p := range payload {
+		ch <- etl.NewMessage(p)
+	}
+	close(ch)
+
+	return ch
+}
+
+func TestMux_MergesAllInputs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	extractor1 := etl.NewExtractor(newFakeExtractor(1, 2, 3))
+	extractor2 := etl.NewExtractor(newFakeExtractor(4, 5))
+	mux := etl.NewMux([]<-chan etl.Message{extractor1.OutputCh(), extractor2.OutputCh()})
+	l := &fakeLoader{}
+	loader := etl.NewLoader(mux.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor1, extractor2, mux, loader)
+	require.NoError(t, err)
+
+	var payload []interface{}
+	for _, call := range l.calls {
+		payload = append(payload, call.Payload())
+	}
+	require.ElementsMatch(t, []interface{}{1, 2, 3, 4, 5}, payload)
+}
+
+func TestMux_RoundRobin(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mux := etl.NewMux([]<-chan etl.Message{
+		newFilledChannel("a1", "a2", "a3"),
+		newFilledChannel("b1"),
+	})
+	l := &fakeLoader{}
+	loader := etl.NewLoader(mux.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, mux, loader)
+	require.NoError(t, err)
+
+	var payload []interface{}
+	for _, call := range l.calls {
+		payload = append(payload, call.Payload())
+	}
+	require.Equal(t, []interface{}{"a1", "b1", "a2", "a3"}, payload)
+}
+
+func TestMux_Weights(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mux := etl.NewMux([]<-chan etl.Message{
+		newFilledChannel("a1", "a2", "a3", "a4"),
+		newFilledChannel("b1", "b2"),
+	}, etl.MuxWithWeights(2, 1))
+	l := &fakeLoader{}
+	loader := etl.NewLoader(mux.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, mux, loader)
+	require.NoError(t, err)
+
+	var payload []interface{}
+	for _, call := range l.calls {
+		payload = append(payload, call.Payload())
+	}
+	require.Equal(t, []interface{}{"a1", "a2", "b1", "a3", "a4", "b2"}, payload)
+}
+
+func TestMux_FailsWhenContextIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errTest := errors.New("test")
+
+	extractor := etl.NewExtractor(func(ctx context.Context, sender etl.Sender) error {
+		return errTest
+	})
+	mux := etl.NewMux([]<-chan etl.Message{extractor.OutputCh(), make(chan etl.Message)})
+
+	err := etl.RunAll(ctx, extractor, mux)
+	require.Equal(t, errTest, err)
+}
+
+func TestTransformerMux(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	extractor1 := etl.NewExtractor(newFakeExtractor(1))
+	extractor2 := etl.NewExtractor(newFakeExtractor(2))
+	transformer := etl.NewTransformerMux([]<-chan etl.Message{extractor1.OutputCh(), extractor2.OutputCh()}, fakeTransformer)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(transformer.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor1, extractor2, transformer, loader)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(l.calls))
+}
+
+func TestMux_WithOrderMergesSortedInputs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mux := etl.NewMux(
+		[]<-chan etl.Message{
+			newFilledChannel(1, 4, 7),
+			newFilledChannel(),
+			newFilledChannel(2, 2, 8, 9),
+			newFilledChannel(3, 5, 6),
+		},
+		etl.MuxWithOrder(func(a, b etl.Message) bool { return a.Payload().(int) < b.Payload().(int) }),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(mux.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, mux, loader)
+	require.NoError(t, err)
+
+	var payload []interface{}
+	for _, call := range l.calls {
+		payload = append(payload, call.Payload())
+	}
+	require.Equal(t, []interface{}{1, 2, 2, 3, 4, 5, 6, 7, 8, 9}, payload)
+}
+
+func TestMux_WithOrderWaitsForSlowInputs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	slowCh := make(chan etl.Message)
+	mux := etl.NewMux(
+		[]<-chan etl.Message{newFilledChannel(2, 4), slowCh},
+		etl.MuxWithOrder(func(a, b etl.Message) bool { return a.Payload().(int) < b.Payload().(int) }),
+	)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- mux.Run(ctx)
+	}()
+
+	// nothing can be sent, until the slow input has a message available or is closed
+	select {
+	case msg := <-mux.OutputCh():
+		require.Failf(t, "unexpected message", "%v", msg.Payload())
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	slowCh <- etl.NewMessage(1)
+	require.Equal(t, 1, (<-mux.OutputCh()).Payload())
+
+	slowCh <- etl.NewMessage(3)
+	require.Equal(t, 2, (<-mux.OutputCh()).Payload())
+	require.Equal(t, 3, (<-mux.OutputCh()).Payload())
+
+	close(slowCh)
+	require.Equal(t, 4, (<-mux.OutputCh()).Payload())
+
+	_, ok := <-mux.OutputCh()
+	require.False(t, ok)
+	require.NoError(t, <-errCh)
+}
+
+func TestMux_SendsLowestWatermarkOfOpenInputs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fastCh := make(chan etl.Message)
+	slowCh := make(chan etl.Message)
+	mux := etl.NewMux([]<-chan etl.Message{fastCh, slowCh})
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- mux.Run(ctx)
+	}()
+
+	for _, tt := range []struct {
+		inputCh   chan etl.Message
+		watermark time.Time
+		want      time.Time
+	}{
+		// the slow input hasn't sent any watermark yet
+		{inputCh: fastCh, watermark: time.Unix(50, 0), want: time.Time{}},
+		{inputCh: slowCh, watermark: time.Unix(10, 0), want: time.Unix(10, 0)},
+		{inputCh: fastCh, watermark: time.Unix(60, 0), want: time.Unix(10, 0)},
+		{inputCh: slowCh, watermark: time.Unix(20, 0), want: time.Unix(20, 0)},
+	} {
+		tt.inputCh <- etl.NewMessage(1, etl.MessageWithWatermark(tt.watermark))
+		require.Equal(t, tt.want, (<-mux.OutputCh()).Watermark())
+	}
+
+	close(fastCh)
+	close(slowCh)
+	_, ok := <-mux.OutputCh()
+	require.False(t, ok)
+	require.NoError(t, <-errCh)
+}
+
+func TestMux_WithOrderSendsLowestWatermarkOfOpenInputs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fastCh := make(chan etl.Message, 1)
+	slowCh := make(chan etl.Message, 1)
+	mux := etl.NewMux(
+		[]<-chan etl.Message{fastCh, slowCh},
+		etl.MuxWithOrder(func(a, b etl.Message) bool { return a.Payload().(int) < b.Payload().(int) }),
+	)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- mux.Run(ctx)
+	}()
+
+	fastCh <- etl.NewMessage(1, etl.MessageWithWatermark(time.Unix(50, 0)))
+	slowCh <- etl.NewMessage(2, etl.MessageWithWatermark(time.Unix(10, 0)))
+	msg := <-mux.OutputCh()
+	require.Equal(t, 1, msg.Payload())
+	require.Equal(t, time.Unix(10, 0), msg.Watermark())
+
+	fastCh <- etl.NewMessage(3, etl.MessageWithWatermark(time.Unix(60, 0)))
+	msg = <-mux.OutputCh()
+	require.Equal(t, 2, msg.Payload())
+	require.Equal(t, time.Unix(10, 0), msg.Watermark())
+
+	// once the slow input is closed, it no longer holds the watermark back
+	close(slowCh)
+	msg = <-mux.OutputCh()
+	require.Equal(t, 3, msg.Payload())
+	require.Equal(t, time.Unix(60, 0), msg.Watermark())
+
+	close(fastCh)
+	_, ok := <-mux.OutputCh()
+	require.False(t, ok)
+	require.NoError(t, <-errCh)
+}
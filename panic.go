@@ -0,0 +1,41 @@
+package etl
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is returned when a handler or batcher panics. It's handled as any other handler error.
+type PanicError struct {
+	// Value passed to panic
+	Value interface{}
+	// Stack trace of the goroutine that panicked
+	Stack []byte
+	// Messages being processed when panic occurred. Empty for extractors and batchers
+	Messages []Message
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("recovered from panic: %v", e.Value)
+}
+
+// Unwrap returns value passed to panic if it's an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// callRecovering calls fn, converting a panic into PanicError
+func callRecovering(fn func() error, msgs ...Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{
+				Value:    r,
+				Stack:    debug.Stack(),
+				Messages: msgs,
+			}
+		}
+	}()
+
+	return fn()
+}
@@ -0,0 +1,85 @@
+package etl_test
+
+import (
+	"context"
+	"errors"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPanic_ExtractorReturnsPanicError(t *testing.T) {
+	extractor := etl.NewExtractor(func(ctx context.Context, sender etl.Sender) error {
+		panic("test")
+	})
+
+	err := extractor.Run(context.Background())
+
+	var panicErr *etl.PanicError
+	require.True(t, errors.As(err, &panicErr))
+	require.Equal(t, "test", panicErr.Value)
+	require.NotEmpty(t, panicErr.Stack)
+	require.Empty(t, panicErr.Messages)
+}
+
+func TestPanic_TransformerFailsWithPanicError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	extractor := etl.NewExtractor(newFakeExtractor(1))
+	transformer := etl.NewTransformer(extractor.OutputCh(), func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+		panic("test")
+	})
+	drainer := &fakeDrainer{inMsgCh: transformer.OutputCh()}
+
+	err := etl.RunAll(ctx, extractor, transformer, drainer)
+
+	var panicErr *etl.PanicError
+	require.True(t, errors.As(err, &panicErr))
+	require.Equal(t, 1, len(panicErr.Messages))
+	require.Equal(t, 1, panicErr.Messages[0].Payload())
+}
+
+func TestPanic_LoaderAppliesErrorHandling(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		errTest     = errors.New("test")
+		hook        = fakeLoaderHook{}
+		deadLetters = &fakeLoader{}
+	)
+
+	extractor := etl.NewExtractor(newFakeExtractor(1, 2))
+	loader := etl.NewLoader(extractor.OutputCh(), func(ctx context.Context, message etl.Message) error {
+		panic(errTest)
+	},
+		etl.LoaderWithFailOnError(false),
+		etl.LoaderWithOnErrorHook(hook.OnError),
+		etl.LoaderWithDeadLetterSink(etl.DeadLetterToLoader(deadLetters.Handle)),
+	)
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(hook.onErrorCalls))
+	require.True(t, errors.Is(hook.onErrorCalls[0], errTest))
+	require.Equal(t, 2, len(deadLetters.calls))
+}
+
+func TestPanic_LoaderBatchedRecoversBatcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	extractor := etl.NewExtractor(newFakeExtractor(1))
+	loader := etl.NewLoaderBatched(extractor.OutputCh(), func(ctx context.Context, messages []etl.Message) error {
+		return nil
+	}, etl.LoaderBatchedWithBatcher(func(ctx context.Context, inMsgCh <-chan etl.Message) ([]etl.Message, error) {
+		panic("test")
+	}))
+
+	err := etl.RunAll(ctx, extractor, loader)
+
+	var panicErr *etl.PanicError
+	require.True(t, errors.As(err, &panicErr))
+	require.Equal(t, "test", panicErr.Value)
+}
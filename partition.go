@@ -0,0 +1,70 @@
+package etl
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// PartitionKeyFunc returns partition key of a message. Messages with the same key are processed sequentially by the
+// same worker.
+type PartitionKeyFunc func(msg Message) string
+
+// atLeastOne clamps number of workers or partitions, so there's always at least one
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+
+	return n
+}
+
+func partitionIndex(key string, partitionsNr int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	return int(h.Sum32() % uint32(partitionsNr))
+}
+
+func newPartitionChannels(partitionsNr int) ([]chan Message, []<-chan Message) {
+	var (
+		chs      = make([]chan Message, partitionsNr)
+		inputChs = make([]<-chan Message, partitionsNr)
+	)
+	for i := range chs {
+		chs[i] = make(chan Message)
+		inputChs[i] = chs[i]
+	}
+
+	return chs, inputChs
+}
+
+// partitionMessages distributes messages from the input channel across partitions by key. Partition channels are
+// closed once input channel is closed.
+func partitionMessages(ctx context.Context, inputCh <-chan Message, keyFn PartitionKeyFunc, partitionChs []chan Message) error {
+	defer func() {
+		for _, ch := range partitionChs {
+			close(ch)
+		}
+	}()
+
+	var (
+		msg Message
+		ok  bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok = <-inputCh:
+			if !ok {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case partitionChs[partitionIndex(keyFn(msg), len(partitionChs))] <- msg:
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package etl_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newKeyedExtractor(keys []string, perKey int) etl.ExtractorHandler {
+	return func(ctx context.Context, sender etl.Sender) error {
+		for i := 0; i < perKey; i++ {
+			for _, key := range keys {
+				err := sender.SendMessage(ctx, etl.NewMessage(i, etl.MessageWithHeader("key", key)))
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+}
+
+func keyFromHeader(msg etl.Message) string {
+	return msg.Header("key")
+}
+
+// fakeKeyedRecorder records payloads per key and detects concurrent processing of the same key
+type fakeKeyedRecorder struct {
+	sync.Mutex
+	inFlight   map[string]bool
+	payloads   map[string][]interface{}
+	overlapped bool
+}
+
+func newFakeKeyedRecorder() *fakeKeyedRecorder {
+	return &fakeKeyedRecorder{
+		inFlight: make(map[string]bool),
+		payloads: make(map[string][]interface{}),
+	}
+}
+
+func (f *fakeKeyedRecorder) Record(msg etl.Message) {
+	key := msg.Header("key")
+
+	f.Lock()
+	if f.inFlight[key] {
+		f.overlapped = true
+	}
+	f.inFlight[key] = true
+	f.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	f.Lock()
+	f.inFlight[key] = false
+	f.payloads[key] = append(f.payloads[key], msg.Payload())
+	f.Unlock()
+}
+
+func requireOrderedPerKey(t *testing.T, recorder *fakeKeyedRecorder, keys []string, perKey int) {
+	require.False(t, recorder.overlapped, "expected messages with the same key to be processed sequentially")
+	for _, key := range keys {
+		require.Equal(t, perKey, len(recorder.payloads[key]))
+		for i := 0; i < perKey; i++ {
+			require.Equal(t, i, recorder.payloads[key][i])
+		}
+	}
+}
+
+func TestPartition_Loader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keys := []string{"a", "b", "c", "d", "e"}
+	recorder := newFakeKeyedRecorder()
+
+	extractor := etl.NewExtractor(newKeyedExtractor(keys, 10))
+	loader := etl.NewLoader(extractor.OutputCh(), func(ctx context.Context, message etl.Message) error {
+		recorder.Record(message)
+		return nil
+	}, etl.LoaderWithConcurrency(3), etl.LoaderWithPartitionKey(keyFromHeader))
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.NoError(t, err)
+	requireOrderedPerKey(t, recorder, keys, 10)
+}
+
+func TestPartition_LoaderBatched(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keys := []string{"a", "b", "c", "d", "e"}
+	recorder := newFakeKeyedRecorder()
+
+	extractor := etl.NewExtractor(newKeyedExtractor(keys, 10))
+	loader := etl.NewLoaderBatched(extractor.OutputCh(), func(ctx context.Context, messages []etl.Message) error {
+		for _, message := range messages {
+			recorder.Record(message)
+		}
+
+		return nil
+	},
+		etl.LoaderBatchedWithConcurrency(3),
+		etl.LoaderBatchedWithPartitionKey(keyFromHeader),
+		etl.LoaderBatchedWithDrainedChannelBatches(5),
+	)
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.NoError(t, err)
+	requireOrderedPerKey(t, recorder, keys, 10)
+}
+
+func TestPartition_TransformerDemux(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keys := []string{"a", "b", "c", "d", "e"}
+	recorder := newFakeKeyedRecorder()
+
+	extractor := etl.NewExtractor(newKeyedExtractor(keys, 10))
+	transformer := etl.NewTransformerDemux(extractor.OutputCh(), func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+		recorder.Record(inMsg)
+		return sender.SendMessage(ctx, inMsg)
+	}, 1, etl.TransformerWithConcurrency(3), etl.TransformerWithPartitionKey(keyFromHeader))
+	output := newFakeKeyedRecorder()
+	loader := etl.NewLoader(transformer.OutputCh(0), func(ctx context.Context, message etl.Message) error {
+		output.Record(message)
+		return nil
+	})
+
+	err := etl.RunAll(ctx, extractor, transformer, loader)
+	require.NoError(t, err)
+	requireOrderedPerKey(t, recorder, keys, 10)
+	requireOrderedPerKey(t, output, keys, 10)
+}
+
+func TestPartition_TransformerWithOrderedOutput(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keys := []string{"a", "b", "c"}
+
+	extractor := etl.NewExtractor(newKeyedExtractor(keys, 5))
+	transformer := etl.NewTransformer(extractor.OutputCh(), func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+		return sender.Send(ctx, fmt.Sprintf("%s%d", inMsg.Header("key"), inMsg.Payload()))
+	},
+		etl.TransformerWithConcurrency(2),
+		etl.TransformerWithPartitionKey(keyFromHeader),
+		etl.TransformerWithOrderedOutput(4),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(transformer.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor, transformer, loader)
+	require.NoError(t, err)
+	require.Equal(t, 15, len(l.calls))
+	for i, call := range l.calls {
+		require.Equal(t, fmt.Sprintf("%s%d", keys[i%3], i/3), call.Payload())
+	}
+}
+
+func TestPartition_ZeroConcurrencyUsesSingleWorker(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	keys := []string{"a", "b"}
+	loaderRecorder := newFakeKeyedRecorder()
+	batchedRecorder := newFakeKeyedRecorder()
+
+	extractor := etl.NewExtractor(newKeyedExtractor(keys, 5))
+	transformer := etl.NewTransformer(extractor.OutputCh(), func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+		return sender.SendMessage(ctx, inMsg)
+	}, etl.TransformerWithConcurrency(0), etl.TransformerWithPartitionKey(keyFromHeader))
+	broadcast := etl.NewBroadcast(transformer.OutputCh(), 2)
+	loader := etl.NewLoader(broadcast.OutputCh(0), func(ctx context.Context, message etl.Message) error {
+		loaderRecorder.Record(message)
+		return nil
+	}, etl.LoaderWithConcurrency(0), etl.LoaderWithPartitionKey(keyFromHeader))
+	loaderBatched := etl.NewLoaderBatched(broadcast.OutputCh(1), func(ctx context.Context, messages []etl.Message) error {
+		for _, message := range messages {
+			batchedRecorder.Record(message)
+		}
+
+		return nil
+	}, etl.LoaderBatchedWithConcurrency(0), etl.LoaderBatchedWithPartitionKey(keyFromHeader))
+
+	err := etl.RunAll(ctx, extractor, transformer, broadcast, loader, loaderBatched)
+	require.NoError(t, err)
+	requireOrderedPerKey(t, loaderRecorder, keys, 5)
+	requireOrderedPerKey(t, batchedRecorder, keys, 5)
+}
+
+// fakeInFlightRecorder records the highest number of concurrent handler calls
+type fakeInFlightRecorder struct {
+	sync.Mutex
+	current int
+	max     int
+}
+
+func (f *fakeInFlightRecorder) Record() {
+	f.Lock()
+	f.current++
+	if f.current > f.max {
+		f.max = f.current
+	}
+	f.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	f.Lock()
+	f.current--
+	f.Unlock()
+}
+
+func TestConcurrency_StartsConfiguredNumberOfWorkers(t *testing.T) {
+	payload := []interface{}{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	for name, run := range map[string]func(ctx context.Context, recorder *fakeInFlightRecorder) error{
+		"loader": func(ctx context.Context, recorder *fakeInFlightRecorder) error {
+			loader := etl.NewLoader(newFilledChannel(payload...), func(ctx context.Context, message etl.Message) error {
+				recorder.Record()
+				return nil
+			}, etl.LoaderWithConcurrency(2))
+
+			return loader.Run(ctx)
+		},
+		"loader batched": func(ctx context.Context, recorder *fakeInFlightRecorder) error {
+			loader := etl.NewLoaderBatched(newFilledChannel(payload...), func(ctx context.Context, messages []etl.Message) error {
+				recorder.Record()
+				return nil
+			}, etl.LoaderBatchedWithConcurrency(2), etl.LoaderBatchedWithFixedSizeBatches(1))
+
+			return loader.Run(ctx)
+		},
+		"transformer": func(ctx context.Context, recorder *fakeInFlightRecorder) error {
+			transformer := etl.NewTransformer(newFilledChannel(payload...), func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+				recorder.Record()
+				return sender.SendMessage(ctx, inMsg)
+			}, etl.TransformerWithConcurrency(2))
+			loader := etl.NewLoader(transformer.OutputCh(), (&fakeLoader{}).Handle)
+
+			return etl.RunAll(ctx, transformer, loader)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			recorder := &fakeInFlightRecorder{}
+			require.NoError(t, run(ctx, recorder))
+			require.Equal(t, 2, recorder.max)
+		})
+	}
+}
@@ -0,0 +1,421 @@
+package etl
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+// Pipeline wires stages together. Stages are registered by name and connected with From/To, channels between them
+// are created by the pipeline.
+//
+//	p := etl.NewPipeline()
+//	p.AddExtractor("src", extract)
+//	p.AddTransformer("enrich", enrich)
+//	p.AddLoader("sink", load)
+//	p.From("src").To("enrich").To("sink")
+//
+//	err := p.Run(ctx)
+type Pipeline struct {
+	stages map[string]*pipelineStage
+	order  []string
+	links  []pipelineLink
+	err    error
+
+	groupMu sync.Mutex
+	group   *Group
+
+	opts *pipelineOptions
+}
+
+type pipelineStageBuilder func(inputCh <-chan Message, bufferSize int) (Runner, []<-chan Message)
+
+type pipelineStage struct {
+	name        string
+	hasInput    bool
+	outputChsNr uint
+	build       pipelineStageBuilder
+}
+
+type pipelineLink struct {
+	from    string
+	outChNr uint
+	to      string
+}
+
+// PipelineLink is returned by Pipeline.From and PipelineLink.To to allow chaining connections.
+type PipelineLink struct {
+	p       *Pipeline
+	from    string
+	outChNr uint
+}
+
+func NewPipeline(optsSetters ...PipelineOption) *Pipeline {
+	return &Pipeline{
+		stages: make(map[string]*pipelineStage),
+		group:  newGroup(),
+		opts:   newPipelineOptions(optsSetters...),
+	}
+}
+
+func (p *Pipeline) addStage(stage *pipelineStage) *Pipeline {
+	if _, ok := p.stages[stage.name]; ok {
+		if p.err == nil {
+			p.err = errors.Wrapf(ErrPipelineDuplicatedStage, "stage %q", stage.name)
+		}
+
+		return p
+	}
+
+	p.stages[stage.name] = stage
+	p.order = append(p.order, stage.name)
+
+	return p
+}
+
+func (p *Pipeline) AddExtractor(name string, handler ExtractorHandler, optsSetters ...ExtractorOption) *Pipeline {
+	return p.addStage(&pipelineStage{
+		name:        name,
+		outputChsNr: 1,
+		build: func(_ <-chan Message, bufferSize int) (Runner, []<-chan Message) {
+			e := NewExtractor(handler, append([]ExtractorOption{ExtractorWithOutputChannelBufferSize(bufferSize)}, optsSetters...)...)
+
+			return e, []<-chan Message{e.OutputCh()}
+		},
+	})
+}
+
+func (p *Pipeline) AddTransformer(name string, handler TransformerHandler, optsSetters ...TransformerOption) *Pipeline {
+	return p.AddTransformerDemux(name, handler, 1, optsSetters...)
+}
+
+func (p *Pipeline) AddTransformerDemux(name string, handler TransformerHandler, outputChannelsNr uint, optsSetters ...TransformerOption) *Pipeline {
+	return p.addStage(&pipelineStage{
+		name:        name,
+		hasInput:    true,
+		outputChsNr: outputChannelsNr,
+		build: func(inputCh <-chan Message, bufferSize int) (Runner, []<-chan Message) {
+			t := NewTransformerDemux(inputCh, handler, outputChannelsNr, append([]TransformerOption{
+				TransformerWithName(name),
+				TransformerWithOutputChannelBufferSize(bufferSize),
+			}, optsSetters...)...)
+
+			outputChs := make([]<-chan Message, outputChannelsNr)
+			for i := range outputChs {
+				outputChs[i] = t.OutputCh(i)
+			}
+
+			return t, outputChs
+		},
+	})
+}
+
+func (p *Pipeline) AddTransformerBatched(name string, handler TransformerBatchedHandler, optsSetters ...TransformerBatchedOption) *Pipeline {
+	return p.addStage(&pipelineStage{
+		name:        name,
+		hasInput:    true,
+		outputChsNr: 1,
+		build: func(inputCh <-chan Message, bufferSize int) (Runner, []<-chan Message) {
+			t := NewTransformerBatched(inputCh, handler, append([]TransformerBatchedOption{
+				TransformerBatchedWithName(name),
+				TransformerBatchedWithOutputChannelBufferSize(bufferSize),
+			}, optsSetters...)...)
+
+			return t, []<-chan Message{t.OutputCh()}
+		},
+	})
+}
+
+func (p *Pipeline) AddWindowing(name string, windowType WindowType, handler WindowHandler, optsSetters ...WindowingOption) *Pipeline {
+	return p.addStage(&pipelineStage{
+		name:        name,
+		hasInput:    true,
+		outputChsNr: 1,
+		build: func(inputCh <-chan Message, bufferSize int) (Runner, []<-chan Message) {
+			w := NewWindowing(inputCh, windowType, handler, append([]WindowingOption{
+				WindowingWithName(name),
+				WindowingWithOutputChannelBufferSize(bufferSize),
+			}, optsSetters...)...)
+
+			return w, []<-chan Message{w.OutputCh()}
+		},
+	})
+}
+
+func (p *Pipeline) AddDeduplicator(name string, keyFn PartitionKeyFunc, ttl time.Duration, optsSetters ...DeduplicatorOption) *Pipeline {
+	return p.addStage(&pipelineStage{
+		name:        name,
+		hasInput:    true,
+		outputChsNr: 1,
+		build: func(inputCh <-chan Message, bufferSize int) (Runner, []<-chan Message) {
+			d := NewDeduplicator(inputCh, keyFn, ttl, append([]DeduplicatorOption{DeduplicatorWithOutputChannelBufferSize(bufferSize)}, optsSetters...)...)
+
+			return d, []<-chan Message{d.OutputCh()}
+		},
+	})
+}
+
+func (p *Pipeline) AddAggregator(name string, keyFn PartitionKeyFunc, reducer Reducer, optsSetters ...AggregatorOption) *Pipeline {
+	return p.addStage(&pipelineStage{
+		name:        name,
+		hasInput:    true,
+		outputChsNr: 1,
+		build: func(inputCh <-chan Message, bufferSize int) (Runner, []<-chan Message) {
+			a := NewAggregator(inputCh, keyFn, reducer, append([]AggregatorOption{AggregatorWithOutputChannelBufferSize(bufferSize)}, optsSetters...)...)
+
+			return a, []<-chan Message{a.OutputCh()}
+		},
+	})
+}
+
+func (p *Pipeline) AddSorter(name string, less MessageLessFunc, optsSetters ...SorterOption) *Pipeline {
+	return p.addStage(&pipelineStage{
+		name:        name,
+		hasInput:    true,
+		outputChsNr: 1,
+		build: func(inputCh <-chan Message, bufferSize int) (Runner, []<-chan Message) {
+			s := NewSorter(inputCh, less, append([]SorterOption{SorterWithOutputChannelBufferSize(bufferSize)}, optsSetters...)...)
+
+			return s, []<-chan Message{s.OutputCh()}
+		},
+	})
+}
+
+func (p *Pipeline) AddLoader(name string, handler LoaderHandler, optsSetters ...LoaderOption) *Pipeline {
+	return p.addStage(&pipelineStage{
+		name:     name,
+		hasInput: true,
+		build: func(inputCh <-chan Message, _ int) (Runner, []<-chan Message) {
+			return NewLoader(inputCh, handler, append([]LoaderOption{LoaderWithName(name)}, optsSetters...)...), nil
+		},
+	})
+}
+
+func (p *Pipeline) AddLoaderBatched(name string, handler LoaderBatchedHandler, optsSetters ...LoaderBatchedOption) *Pipeline {
+	return p.addStage(&pipelineStage{
+		name:     name,
+		hasInput: true,
+		build: func(inputCh <-chan Message, _ int) (Runner, []<-chan Message) {
+			return NewLoaderBatched(inputCh, handler, append([]LoaderBatchedOption{LoaderBatchedWithName(name)}, optsSetters...)...), nil
+		},
+	})
+}
+
+func (p *Pipeline) AddBroadcast(name string, outputChannelsNr uint, optsSetters ...BroadcastOption) *Pipeline {
+	return p.addStage(&pipelineStage{
+		name:        name,
+		hasInput:    true,
+		outputChsNr: outputChannelsNr,
+		build: func(inputCh <-chan Message, bufferSize int) (Runner, []<-chan Message) {
+			b := NewBroadcast(inputCh, outputChannelsNr, append([]BroadcastOption{BroadcastWithOutputChannelBufferSize(bufferSize)}, optsSetters...)...)
+
+			outputChs := make([]<-chan Message, outputChannelsNr)
+			for i := range outputChs {
+				outputChs[i] = b.OutputCh(i)
+			}
+
+			return b, outputChs
+		},
+	})
+}
+
+// From starts a connection from the first output of a stage
+func (p *Pipeline) From(name string) *PipelineLink {
+	return p.FromCh(name, 0)
+}
+
+// FromCh starts a connection from the specified output of a stage
+func (p *Pipeline) FromCh(name string, outChNr uint) *PipelineLink {
+	return &PipelineLink{p: p, from: name, outChNr: outChNr}
+}
+
+// To connects stage to the output of previous one and returns a link starting from its first output
+func (l *PipelineLink) To(name string) *PipelineLink {
+	l.p.links = append(l.p.links, pipelineLink{from: l.from, outChNr: l.outChNr, to: name})
+
+	return l.p.From(name)
+}
+
+// Validate checks that all stages are connected correctly
+func (p *Pipeline) Validate() error {
+	_, err := p.inputs()
+	return err
+}
+
+// inputs validates pipeline graph and returns source of input for every stage that accepts one
+func (p *Pipeline) inputs() (map[string]pipelineLink, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	if len(p.stages) == 0 {
+		return nil, ErrPipelineEmpty
+	}
+
+	type output struct {
+		stage   string
+		outChNr uint
+	}
+
+	var (
+		inputs    = make(map[string]pipelineLink)
+		connected = make(map[output]struct{})
+	)
+	for _, link := range p.links {
+		from, ok := p.stages[link.from]
+		if !ok {
+			return nil, errors.Wrapf(ErrPipelineStageNotFound, "stage %q", link.from)
+		}
+
+		to, ok := p.stages[link.to]
+		if !ok {
+			return nil, errors.Wrapf(ErrPipelineStageNotFound, "stage %q", link.to)
+		}
+
+		if from.outputChsNr == 0 {
+			return nil, errors.Wrapf(ErrPipelineStageHasNoOutput, "stage %q", link.from)
+		}
+
+		if link.outChNr >= from.outputChsNr {
+			return nil, errors.Wrapf(ErrOutputMessageOutOfChannelsRange, "stage %q output %d", link.from, link.outChNr)
+		}
+
+		if !to.hasInput {
+			return nil, errors.Wrapf(ErrPipelineStageHasNoInput, "stage %q", link.to)
+		}
+
+		out := output{stage: link.from, outChNr: link.outChNr}
+		if _, ok = connected[out]; ok {
+			return nil, errors.Wrapf(ErrPipelineOutputConsumedTwice, "stage %q output %d", link.from, link.outChNr)
+		}
+		connected[out] = struct{}{}
+
+		if _, ok = inputs[link.to]; ok {
+			return nil, errors.Wrapf(ErrPipelineStageConsumesTwice, "stage %q", link.to)
+		}
+		inputs[link.to] = link
+	}
+
+	for _, name := range p.order {
+		stage := p.stages[name]
+
+		if _, ok := inputs[name]; stage.hasInput && !ok {
+			return nil, errors.Wrapf(ErrPipelineMissingInput, "stage %q", name)
+		}
+
+		for i := uint(0); i < stage.outputChsNr; i++ {
+			if _, ok := connected[output{stage: name, outChNr: i}]; !ok {
+				return nil, errors.Wrapf(ErrPipelineDanglingOutput, "stage %q output %d", name, i)
+			}
+		}
+	}
+
+	// every stage has exactly one input, so a stage that can't be reached from an extractor is a part of a cycle
+	var (
+		reachable = make(map[string]struct{})
+		queue     []string
+	)
+	for _, name := range p.order {
+		if !p.stages[name].hasInput {
+			reachable[name] = struct{}{}
+			queue = append(queue, name)
+		}
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		for _, link := range p.links {
+			if _, ok := reachable[link.to]; link.from == name && !ok {
+				reachable[link.to] = struct{}{}
+				queue = append(queue, link.to)
+			}
+		}
+	}
+	for _, name := range p.order {
+		if _, ok := reachable[name]; !ok {
+			return nil, errors.Wrapf(ErrPipelineUnreachableStage, "stage %q", name)
+		}
+	}
+
+	return inputs, nil
+}
+
+// build creates stages in order, so every stage is created after the one it consumes from
+func (p *Pipeline) build(inputs map[string]pipelineLink) []Runner {
+	var (
+		runners   = make([]Runner, 0, len(p.stages))
+		outputChs = make(map[string][]<-chan Message, len(p.stages))
+		built     = make(map[string]struct{}, len(p.stages))
+	)
+	for len(built) < len(p.stages) {
+		for _, name := range p.order {
+			if _, ok := built[name]; ok {
+				continue
+			}
+
+			var inputCh <-chan Message
+			if link, ok := inputs[name]; ok {
+				chs, ok := outputChs[link.from]
+				if !ok {
+					continue
+				}
+
+				inputCh = chs[link.outChNr]
+			}
+
+			runner, chs := p.stages[name].build(inputCh, p.opts.channelBufferSize)
+			runners = append(runners, runner)
+			outputChs[name] = chs
+			built[name] = struct{}{}
+		}
+	}
+
+	return runners
+}
+
+// Run validates the pipeline and runs all of its stages. Note that execution of this function is blocking, until processing is finished.
+func (p *Pipeline) Run(ctx context.Context) error {
+	inputs, err := p.inputs()
+	if err != nil {
+		return errors.Wrap(err, "invalid pipeline")
+	}
+
+	return p.runGroup().run(ctx, p.build(inputs))
+}
+
+// runGroup returns group for the next run. Stages are rebuilt on every run, so a group that has already been run is
+// replaced with a new one
+func (p *Pipeline) runGroup() *Group {
+	p.groupMu.Lock()
+	defer p.groupMu.Unlock()
+
+	p.group.Lock()
+	started := p.group.started
+	p.group.Unlock()
+
+	if started {
+		p.group = newGroup()
+	}
+
+	return p.group
+}
+
+// currentGroup returns group of the current or the next run
+func (p *Pipeline) currentGroup() *Group {
+	p.groupMu.Lock()
+	defer p.groupMu.Unlock()
+
+	return p.group
+}
+
+// Drain stops extractors and waits until the rest of the pipeline processes messages already in flight. See Group.Drain
+func (p *Pipeline) Drain(ctx context.Context) error {
+	return p.currentGroup().Drain(ctx)
+}
+
+// Shutdown drains the pipeline, cancelling it if it doesn't finish within the grace period
+func (p *Pipeline) Shutdown(gracePeriod time.Duration) error {
+	return p.currentGroup().Shutdown(gracePeriod)
+}
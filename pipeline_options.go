@@ -0,0 +1,25 @@
+package etl
+
+type pipelineOptions struct {
+	channelBufferSize int
+}
+
+func newPipelineOptions(optsSetters ...PipelineOption) *pipelineOptions {
+	opts := &pipelineOptions{}
+
+	for _, setter := range optsSetters {
+		if setter != nil {
+			setter(opts)
+		}
+	}
+
+	return opts
+}
+
+type PipelineOption func(o *pipelineOptions)
+
+// PipelineWithChannelBufferSize sets buffer size of channels connecting stages. It can be overridden per stage
+// with stage's own buffer size option.
+func PipelineWithChannelBufferSize(size int) PipelineOption {
+	return func(o *pipelineOptions) { o.channelBufferSize = size }
+}
@@ -0,0 +1,162 @@
+package etl_test
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPipeline_RunsConnectedStages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l := &fakeLoader{}
+
+	p := etl.NewPipeline(etl.PipelineWithChannelBufferSize(2))
+	p.AddExtractor("src", newFakeExtractor(1, 2))
+	p.AddTransformer("double", fakeTransformer)
+	p.AddLoader("sink", l.Handle)
+	p.From("src").To("double").To("sink")
+
+	err := p.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(l.calls))
+	require.Equal(t, 2, l.calls[0].Payload())
+	require.Equal(t, 4, l.calls[1].Payload())
+}
+
+func TestPipeline_RunsDemuxOutputs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	even, odd := &fakeLoader{}, &fakeLoader{}
+
+	p := etl.NewPipeline()
+	p.AddExtractor("src", newFakeExtractor(1, 2, 3))
+	p.AddTransformerDemux("split", func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+		return sender.SendCh(ctx, uint(inMsg.Payload().(int)%2), inMsg.Payload())
+	}, 2)
+	p.AddLoader("even", even.Handle)
+	p.AddLoader("odd", odd.Handle)
+	p.From("src").To("split")
+	p.FromCh("split", 0).To("even")
+	p.FromCh("split", 1).To("odd")
+
+	err := p.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(even.calls))
+	require.Equal(t, 2, len(odd.calls))
+}
+
+func TestPipeline_Validate(t *testing.T) {
+	handler := func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error { return nil }
+	loader := func(ctx context.Context, message etl.Message) error { return nil }
+
+	tests := []struct {
+		name    string
+		build   func(p *etl.Pipeline)
+		wantErr error
+	}{
+		{
+			name:    "empty",
+			build:   func(p *etl.Pipeline) {},
+			wantErr: etl.ErrPipelineEmpty,
+		},
+		{
+			name: "duplicated stage",
+			build: func(p *etl.Pipeline) {
+				p.AddExtractor("src", newFakeExtractor()).AddExtractor("src", newFakeExtractor())
+			},
+			wantErr: etl.ErrPipelineDuplicatedStage,
+		},
+		{
+			name: "unknown stage",
+			build: func(p *etl.Pipeline) {
+				p.AddExtractor("src", newFakeExtractor())
+				p.From("src").To("sink")
+			},
+			wantErr: etl.ErrPipelineStageNotFound,
+		},
+		{
+			name: "dangling demux output",
+			build: func(p *etl.Pipeline) {
+				p.AddExtractor("src", newFakeExtractor())
+				p.AddTransformerDemux("split", handler, 2)
+				p.AddLoader("sink", loader)
+				p.From("src").To("split").To("sink")
+			},
+			wantErr: etl.ErrPipelineDanglingOutput,
+		},
+		{
+			name: "output out of range",
+			build: func(p *etl.Pipeline) {
+				p.AddExtractor("src", newFakeExtractor())
+				p.AddLoader("sink", loader)
+				p.FromCh("src", 1).To("sink")
+			},
+			wantErr: etl.ErrOutputMessageOutOfChannelsRange,
+		},
+		{
+			name: "stage consumes twice",
+			build: func(p *etl.Pipeline) {
+				p.AddExtractor("src1", newFakeExtractor())
+				p.AddExtractor("src2", newFakeExtractor())
+				p.AddLoader("sink", loader)
+				p.From("src1").To("sink")
+				p.From("src2").To("sink")
+			},
+			wantErr: etl.ErrPipelineStageConsumesTwice,
+		},
+		{
+			name: "output consumed twice",
+			build: func(p *etl.Pipeline) {
+				p.AddExtractor("src", newFakeExtractor())
+				p.AddLoader("sink1", loader)
+				p.AddLoader("sink2", loader)
+				p.From("src").To("sink1")
+				p.From("src").To("sink2")
+			},
+			wantErr: etl.ErrPipelineOutputConsumedTwice,
+		},
+		{
+			name: "missing input",
+			build: func(p *etl.Pipeline) {
+				p.AddLoader("sink", loader)
+			},
+			wantErr: etl.ErrPipelineMissingInput,
+		},
+		{
+			name: "extractor as a target",
+			build: func(p *etl.Pipeline) {
+				p.AddExtractor("src1", newFakeExtractor())
+				p.AddExtractor("src2", newFakeExtractor())
+				p.From("src1").To("src2")
+			},
+			wantErr: etl.ErrPipelineStageHasNoInput,
+		},
+		{
+			name: "cycle",
+			build: func(p *etl.Pipeline) {
+				p.AddTransformer("a", handler)
+				p.AddTransformer("b", handler)
+				p.From("a").To("b").To("a")
+			},
+			wantErr: etl.ErrPipelineUnreachableStage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := etl.NewPipeline()
+			tt.build(p)
+
+			err := p.Validate()
+			require.Equal(t, tt.wantErr, errors.Cause(err))
+
+			err = p.Run(context.Background())
+			require.Equal(t, tt.wantErr, errors.Cause(err))
+		})
+	}
+}
@@ -51,8 +51,6 @@ func (q *Queue) enquer(ctx context.Context) error {
 			}
 		}
 	}
-
-	return nil
 }
 
 func (q *Queue) Run(ctx context.Context) error {
@@ -156,6 +156,4 @@ func (q *queueDriverDefault) Run(ctx context.Context) error {
 		case <-q.enqueuedCh:
 		}
 	}
-
-	return nil
 }
@@ -0,0 +1,56 @@
+package etl
+
+import "math"
+
+// Reducer folds messages into an aggregate
+type Reducer struct {
+	// Init returns an empty aggregate
+	Init func() interface{}
+	// Add adds message to the aggregate, returning the updated aggregate
+	Add func(acc interface{}, msg Message) (interface{}, error)
+	// Merge combines two partial aggregates of the same key. It's required only if aggregates might be spilled to disk
+	Merge func(acc, other interface{}) (interface{}, error)
+}
+
+// ValueFunc extracts a numeric value of a message
+type ValueFunc func(msg Message) float64
+
+// CountReducer counts messages, as int
+func CountReducer() Reducer {
+	return Reducer{
+		Init: func() interface{} { return 0 },
+		Add: func(acc interface{}, _ Message) (interface{}, error) {
+			return acc.(int) + 1, nil
+		},
+		Merge: func(acc, other interface{}) (interface{}, error) {
+			return acc.(int) + other.(int), nil
+		},
+	}
+}
+
+// SumReducer sums values of messages
+func SumReducer(valueFn ValueFunc) Reducer {
+	return floatReducer(0, valueFn, func(a, b float64) float64 { return a + b })
+}
+
+// MinReducer finds the lowest value of messages
+func MinReducer(valueFn ValueFunc) Reducer {
+	return floatReducer(math.Inf(1), valueFn, math.Min)
+}
+
+// MaxReducer finds the highest value of messages
+func MaxReducer(valueFn ValueFunc) Reducer {
+	return floatReducer(math.Inf(-1), valueFn, math.Max)
+}
+
+func floatReducer(init float64, valueFn ValueFunc, combine func(a, b float64) float64) Reducer {
+	return Reducer{
+		Init: func() interface{} { return init },
+		Add: func(acc interface{}, msg Message) (interface{}, error) {
+			return combine(acc.(float64), valueFn(msg)), nil
+		},
+		Merge: func(acc, other interface{}) (interface{}, error) {
+			return combine(acc.(float64), other.(float64)), nil
+		},
+	}
+}
@@ -0,0 +1,109 @@
+package etl
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy describes how failed handler calls are retried, before error hooks are called.
+type RetryPolicy struct {
+	// MaxAttempts limits number of handler calls, including the first one. Zero means no limit.
+	MaxAttempts int
+	// InitialInterval is a delay before the first retry.
+	InitialInterval time.Duration
+	// MaxInterval caps a delay between retries. Zero means no cap.
+	MaxInterval time.Duration
+	// Multiplier increases delay after every retry. Defaults to 2.
+	Multiplier float64
+	// Jitter randomizes every delay by up to a given fraction of it, e.g. 0.2 means +/- 20%.
+	Jitter float64
+	// MaxElapsedTime stops retrying once it has passed since the first attempt. Zero means no limit.
+	MaxElapsedTime time.Duration
+	// Retryable decides if an error should be retried. If it's nil, all errors are retried.
+	Retryable func(err error) bool
+}
+
+type attemptCtxKey struct{}
+
+func contextWithAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptCtxKey{}, attempt)
+}
+
+// AttemptFromContext returns number of handler attempts made so far. It's available in handlers and in OnError and
+// OnComplete hooks of stages.
+func AttemptFromContext(ctx context.Context) int {
+	attempt, ok := ctx.Value(attemptCtxKey{}).(int)
+	if !ok {
+		return 1
+	}
+
+	return attempt
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	if err == ErrCastingFailed {
+		return false
+	}
+
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+func (p *RetryPolicy) interval(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	interval := float64(p.InitialInterval) * math.Pow(multiplier, float64(retry))
+	if p.MaxInterval > 0 && interval > float64(p.MaxInterval) {
+		interval = float64(p.MaxInterval)
+	}
+
+	if p.Jitter > 0 {
+		interval += interval * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(interval)
+}
+
+// run calls fn until it succeeds or policy gives up. Returns context with number of attempts made and the last error.
+// A nil policy calls fn once.
+func (p *RetryPolicy) run(ctx context.Context, fn func(ctx context.Context) error) (context.Context, error) {
+	var (
+		startedAt  = time.Now()
+		attempt    = 1
+		attemptCtx context.Context
+		timer      *time.Timer
+		interval   time.Duration
+		err        error
+	)
+	for {
+		attemptCtx = contextWithAttempt(ctx, attempt)
+
+		err = fn(attemptCtx)
+		if err == nil || p == nil || ctx.Err() != nil || !p.retryable(err) {
+			return attemptCtx, err
+		}
+
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			return attemptCtx, err
+		}
+
+		interval = p.interval(attempt - 1)
+		if p.MaxElapsedTime > 0 && time.Since(startedAt)+interval > p.MaxElapsedTime {
+			return attemptCtx, err
+		}
+
+		timer = time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attemptCtx, err
+		case <-timer.C:
+		}
+
+		attempt++
+	}
+}
@@ -0,0 +1,192 @@
+package etl_test
+
+import (
+	"context"
+	"errors"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRetry_LoaderRetriesUntilSuccess(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		calls       int
+		hookAttempt int
+	)
+
+	extractor := etl.NewExtractor(newFakeExtractor(1))
+	loader := etl.NewLoader(extractor.OutputCh(), func(ctx context.Context, message etl.Message) error {
+		calls++
+		if calls < 3 {
+			return errors.New("test")
+		}
+
+		return nil
+	},
+		etl.LoaderWithRetry(etl.RetryPolicy{MaxAttempts: 5, InitialInterval: time.Millisecond}),
+		etl.LoaderWithOnCompleteHook(func(ctx context.Context, inMsg etl.Message) error {
+			hookAttempt = etl.AttemptFromContext(ctx)
+			return nil
+		}),
+	)
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+	require.Equal(t, 3, hookAttempt)
+}
+
+func TestRetry_LoaderBatchedGivesUpAfterMaxAttempts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		errTest     = errors.New("test")
+		calls       int
+		hookAttempt int
+	)
+
+	extractor := etl.NewExtractor(newFakeExtractor(1))
+	loader := etl.NewLoaderBatched(extractor.OutputCh(), func(ctx context.Context, messages []etl.Message) error {
+		calls++
+		return errTest
+	},
+		etl.LoaderBatchedWithRetry(etl.RetryPolicy{MaxAttempts: 3, InitialInterval: time.Millisecond, Jitter: 0.5}),
+		etl.LoaderBatchedWithOnErrorHook(func(ctx context.Context, inMsgs []etl.Message, err error) error {
+			hookAttempt = etl.AttemptFromContext(ctx)
+			return nil
+		}),
+	)
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.Equal(t, errTest, err)
+	require.Equal(t, 3, calls)
+	require.Equal(t, 3, hookAttempt)
+}
+
+func TestRetry_TransformerSkipsNonRetryableErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		errPermanent = errors.New("permanent")
+		calls        int
+	)
+
+	extractor := etl.NewExtractor(newFakeExtractor(1))
+	transformer := etl.NewTransformer(extractor.OutputCh(), func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+		calls++
+		return errPermanent
+	}, etl.TransformerWithRetry(etl.RetryPolicy{
+		MaxAttempts:     5,
+		InitialInterval: time.Millisecond,
+		Retryable: func(err error) bool {
+			return err != errPermanent
+		},
+	}))
+	drainer := &fakeDrainer{inMsgCh: transformer.OutputCh()}
+
+	err := etl.RunAll(ctx, extractor, transformer, drainer)
+	require.Equal(t, errPermanent, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestRetry_StopsAfterMaxElapsedTime(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls int
+
+	extractor := etl.NewExtractor(newFakeExtractor(1))
+	loader := etl.NewLoader(extractor.OutputCh(), func(ctx context.Context, message etl.Message) error {
+		calls++
+		return errors.New("test")
+	}, etl.LoaderWithRetry(etl.RetryPolicy{
+		InitialInterval: 10 * time.Millisecond,
+		Multiplier:      2,
+		MaxElapsedTime:  50 * time.Millisecond,
+	}), etl.LoaderWithFailOnError(false))
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.NoError(t, err)
+	// attempts after 0ms, 10ms and 30ms. Next one would be after 70ms
+	require.Equal(t, 3, calls)
+}
+
+// newFlakySendingHandler sends input payload and fails, until the given number of attempts is reached
+func newFlakySendingHandler(failedAttempts int) etl.TransformerHandler {
+	return func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+		err := sender.Send(ctx, inMsg.Payload())
+		if err != nil {
+			return err
+		}
+
+		if etl.AttemptFromContext(ctx) <= failedAttempts {
+			return errors.New("test")
+		}
+
+		return nil
+	}
+}
+
+func TestRetry_TransformerDiscardsOutputOfFailedAttempts(t *testing.T) {
+	for name, opts := range map[string][]etl.TransformerOption{
+		"unordered": nil,
+		"ordered":   {etl.TransformerWithConcurrency(2), etl.TransformerWithOrderedOutput(0)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			hook := &fakeAckHook{}
+			extractor := etl.NewExtractor(
+				newFakeExtractor(1, 2),
+				etl.ExtractorWithOnAckHook(hook.OnAck),
+				etl.ExtractorWithOnNackHook(hook.OnNack),
+			)
+			transformer := etl.NewTransformer(extractor.OutputCh(), newFlakySendingHandler(2), append([]etl.TransformerOption{
+				etl.TransformerWithRetry(etl.RetryPolicy{MaxAttempts: 3, InitialInterval: time.Millisecond}),
+			}, opts...)...)
+			l := &fakeLoader{}
+			loader := etl.NewLoader(transformer.OutputCh(), l.Handle)
+
+			err := etl.RunAll(ctx, extractor, transformer, loader)
+			require.NoError(t, err)
+			require.Equal(t, []interface{}{1, 2}, payloadsOf(l))
+			require.Equal(t, 2, len(hook.acked))
+			require.Equal(t, 0, len(hook.nacked))
+		})
+	}
+}
+
+func TestRetry_TransformerBatchedDiscardsOutputOfFailedAttempts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	extractor := etl.NewExtractor(newFakeExtractor(1, 2, 3))
+	transformer := etl.NewTransformerBatched(extractor.OutputCh(), func(ctx context.Context, inMsgs []etl.Message, sender etl.Sender) error {
+		err := sender.Send(ctx, len(inMsgs))
+		if err != nil {
+			return err
+		}
+
+		if etl.AttemptFromContext(ctx) < 3 {
+			return errors.New("test")
+		}
+
+		return nil
+	},
+		etl.TransformerBatchedWithFixedSizeBatches(3),
+		etl.TransformerBatchedWithRetry(etl.RetryPolicy{MaxAttempts: 3, InitialInterval: time.Millisecond}),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(transformer.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor, transformer, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{3}, payloadsOf(l))
+}
@@ -0,0 +1,139 @@
+package etl
+
+import (
+	"context"
+	"github.com/pkg/errors"
+)
+
+// RouterPredicate decides whether message should be sent to a route
+type RouterPredicate func(msg Message) bool
+
+// Router sends every message to the first route, which predicate accepts it. Messages not accepted by any route are
+// sent to the default route, or acknowledged and dropped, if there's no default route.
+//
+//	router := etl.NewRouter(extractor.OutputCh()).
+//		Route("errors", isError).
+//		Route("warnings", isWarning).
+//		Default("rest")
+//
+//	errorsCh, err := router.OutputCh("errors")
+type Router interface {
+	Runner
+	// Route registers a route, routes are matched in order of registration
+	Route(name string, predicate RouterPredicate) Router
+	// Default registers a route for messages not accepted by any other route
+	Default(name string) Router
+	OutputCh(name string) (<-chan Message, error)
+}
+
+type routerRoute struct {
+	name      string
+	predicate RouterPredicate
+}
+
+type router struct {
+	inputCh      <-chan Message
+	routes       []routerRoute
+	outputChs    []chan Message
+	defaultRoute int
+	err          error
+
+	opts *transformerOptions
+}
+
+// NewRouter creates a router. It accepts the same options as transformers.
+func NewRouter(inputCh <-chan Message, optsSetters ...TransformerOption) Router {
+	return &router{
+		inputCh:      inputCh,
+		defaultRoute: -1,
+
+		opts: newTransformerOptions(optsSetters...),
+	}
+}
+
+func (r *router) addRoute(name string, predicate RouterPredicate) int {
+	for _, route := range r.routes {
+		if route.name == name {
+			if r.err == nil {
+				r.err = errors.Wrapf(ErrRouterDuplicatedRoute, "route %q", name)
+			}
+
+			return -1
+		}
+	}
+
+	r.routes = append(r.routes, routerRoute{name: name, predicate: predicate})
+	r.outputChs = append(r.outputChs, make(chan Message, r.opts.outputChannelBufferSize))
+
+	return len(r.routes) - 1
+}
+
+func (r *router) Route(name string, predicate RouterPredicate) Router {
+	r.addRoute(name, predicate)
+
+	return r
+}
+
+func (r *router) Default(name string) Router {
+	if r.defaultRoute != -1 {
+		if r.err == nil {
+			r.err = errors.Wrapf(ErrRouterDuplicatedRoute, "default route %q", name)
+		}
+
+		return r
+	}
+
+	r.defaultRoute = r.addRoute(name, nil)
+
+	return r
+}
+
+func (r *router) OutputCh(name string) (<-chan Message, error) {
+	for i, route := range r.routes {
+		if route.name == name {
+			return r.outputChs[i], nil
+		}
+	}
+
+	return nil, errors.Wrapf(ErrRouterRouteNotFound, "route %q", name)
+}
+
+func (r *router) handle(ctx context.Context, inMsg Message, sender Sender) error {
+	for i, route := range r.routes {
+		if route.predicate != nil && route.predicate(inMsg) {
+			return sender.SendChMessage(ctx, uint(i), inMsg)
+		}
+	}
+
+	if r.defaultRoute != -1 {
+		return sender.SendChMessage(ctx, uint(r.defaultRoute), inMsg)
+	}
+
+	return nil
+}
+
+// Run routes messages, until input channel is closed. Note that execution of this function is blocking, until processing is finished.
+func (r *router) Run(ctx context.Context) error {
+	if r.err != nil {
+		r.closeChannels()
+		return errors.Wrap(r.err, "invalid router")
+	}
+
+	t := &transformerDemux{
+		handler: wrapTransformerHandler(r.handle, r.opts.middlewares),
+
+		inputCh:     r.inputCh,
+		outputChsNr: uint(len(r.outputChs)),
+		outputChs:   r.outputChs,
+
+		opts: r.opts,
+	}
+
+	return t.Run(ctx)
+}
+
+func (r *router) closeChannels() {
+	for _, ch := range r.outputChs {
+		close(ch)
+	}
+}
@@ -0,0 +1,91 @@
+package etl_test
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func isEven(msg etl.Message) bool {
+	return msg.Payload().(int)%2 == 0
+}
+
+func isDivisibleByThree(msg etl.Message) bool {
+	return msg.Payload().(int)%3 == 0
+}
+
+func payloadsOf(l *fakeLoader) []interface{} {
+	var payload []interface{}
+	for _, call := range l.calls {
+		payload = append(payload, call.Payload())
+	}
+
+	return payload
+}
+
+func TestRouter_RoutesByPredicates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	router := etl.NewRouter(newFilledChannel(1, 2, 3, 4, 5, 6)).
+		Route("even", isEven).
+		Route("three", isDivisibleByThree).
+		Default("rest")
+
+	var (
+		loaders []etl.Runner
+		fakes   = make(map[string]*fakeLoader)
+	)
+	for _, name := range []string{"even", "three", "rest"} {
+		ch, err := router.OutputCh(name)
+		require.NoError(t, err)
+
+		fakes[name] = &fakeLoader{}
+		loaders = append(loaders, etl.NewLoader(ch, fakes[name].Handle))
+	}
+
+	err := etl.RunAll(ctx, append(loaders, router)...)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{2, 4, 6}, payloadsOf(fakes["even"]))
+	require.Equal(t, []interface{}{3}, payloadsOf(fakes["three"]))
+	require.Equal(t, []interface{}{1, 5}, payloadsOf(fakes["rest"]))
+}
+
+func TestRouter_AcksUnroutedMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hook := &fakeAckHook{}
+	extractor := etl.NewExtractor(newFakeExtractor(1, 2),
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	router := etl.NewRouter(extractor.OutputCh()).Route("even", isEven)
+	ch, err := router.OutputCh("even")
+	require.NoError(t, err)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(ch, l.Handle)
+
+	err = etl.RunAll(ctx, extractor, router, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{2}, payloadsOf(l))
+	require.ElementsMatch(t, []interface{}{1, 2}, hook.acked)
+}
+
+func TestRouter_RouteNotFound(t *testing.T) {
+	router := etl.NewRouter(newFilledChannel()).Route("even", isEven)
+
+	_, err := router.OutputCh("odd")
+	require.Equal(t, etl.ErrRouterRouteNotFound, errors.Cause(err))
+}
+
+func TestRouter_DuplicatedRoute(t *testing.T) {
+	router := etl.NewRouter(newFilledChannel(1)).
+		Route("even", isEven).
+		Route("even", isDivisibleByThree)
+
+	err := router.Run(context.Background())
+	require.Equal(t, etl.ErrRouterDuplicatedRoute, errors.Cause(err))
+}
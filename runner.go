@@ -3,21 +3,128 @@ package etl
 import (
 	"context"
 	"golang.org/x/sync/errgroup"
+	"sync"
+	"time"
 )
 
 type Runner interface {
 	Run(ctx context.Context) error
 }
 
+// Stopper is implemented by runners that seed messages into a pipeline, like extractors. Once stopped, runner
+// stops producing new messages and closes its output, so the rest of the pipeline can finish processing.
+type Stopper interface {
+	Stop()
+}
+
 func RunAll(ctx context.Context, runners ...Runner) error {
-	g, ctx := errgroup.WithContext(ctx)
+	return NewGroup(runners...).Run(ctx)
+}
+
+// Group runs runners together and allows to shut them down gracefully. If any runner fails, the remaining ones are
+// cancelled.
+type Group struct {
+	sync.Mutex
+	runners []Runner
+	started bool
+	stopped bool
+
+	killCh   chan struct{}
+	killOnce sync.Once
+	doneCh   chan struct{}
+	err      error
+}
+
+func NewGroup(runners ...Runner) *Group {
+	g := newGroup()
+	g.runners = runners
+
+	return g
+}
+
+func newGroup() *Group {
+	return &Group{
+		killCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+}
+
+// Run runs all runners. Group can be run only once, subsequent calls return ErrGroupAlreadyRunning. Note that execution of this function is blocking, until processing is finished.
+func (g *Group) Run(ctx context.Context) error {
+	return g.run(ctx, g.runners)
+}
+
+func (g *Group) run(ctx context.Context, runners []Runner) error {
+	g.Lock()
+	if g.started {
+		g.Unlock()
+		return ErrGroupAlreadyRunning
+	}
+	g.started = true
+	g.runners = runners
+	if g.stopped {
+		g.stopRunners()
+	}
+	g.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
+	go func() {
+		select {
+		case <-g.killCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	eg, egCtx := errgroup.WithContext(ctx)
 	for _, runner := range runners {
 		r := runner
-		g.Go(func() error {
-			return r.Run(ctx)
+		eg.Go(func() error {
+			return r.Run(egCtx)
+		})
+	}
+
+	g.err = eg.Wait()
+	close(g.doneCh)
+
+	return g.err
+}
+
+func (g *Group) stopRunners() {
+	for _, runner := range g.runners {
+		if stopper, ok := runner.(Stopper); ok {
+			stopper.Stop()
+		}
+	}
+}
+
+// Drain stops runners implementing Stopper first, and waits until the rest of them finish processing messages
+// already in flight. If ctx is done before that, all runners are cancelled. Returns error returned by Run, or ctx
+// error if runners had to be cancelled.
+func (g *Group) Drain(ctx context.Context) error {
+	g.Lock()
+	g.stopped = true
+	g.stopRunners()
+	g.Unlock()
+
+	select {
+	case <-g.doneCh:
+		return g.err
+	case <-ctx.Done():
+		g.killOnce.Do(func() {
+			close(g.killCh)
 		})
+
+		return ctx.Err()
 	}
+}
+
+// Shutdown drains runners, cancelling them if they don't finish within the grace period
+func (g *Group) Shutdown(gracePeriod time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
 
-	return g.Wait()
+	return g.Drain(ctx)
 }
@@ -0,0 +1,128 @@
+package etl_test
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newInfiniteExtractor(sent *int32) etl.ExtractorHandler {
+	return func(ctx context.Context, sender etl.Sender) error {
+		for i := 0; ; i++ {
+			err := sender.Send(ctx, i)
+			if err != nil {
+				return err
+			}
+
+			atomic.AddInt32(sent, 1)
+		}
+	}
+}
+
+func TestGroup_DrainProcessesMessagesInFlight(t *testing.T) {
+	var sent int32
+
+	extractor := etl.NewExtractor(newInfiniteExtractor(&sent), etl.ExtractorWithOutputChannelBufferSize(10))
+	transformer := etl.NewTransformer(extractor.OutputCh(), fakeTransformer, etl.TransformerWithOutputChannelBufferSize(10))
+	l := &fakeLoaderBatched{}
+	loader := etl.NewLoaderBatched(transformer.OutputCh(), l.Handle, etl.LoaderBatchedWithFixedSizeBatches(1000))
+
+	g := etl.NewGroup(extractor, transformer, loader)
+
+	runErrCh := make(chan error, 1)
+	go func() {
+		runErrCh <- g.Run(context.Background())
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	err := g.Drain(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, <-runErrCh)
+
+	var loaded int
+	for _, call := range l.calls {
+		loaded += len(call)
+	}
+
+	require.NotZero(t, loaded)
+	require.Equal(t, int(atomic.LoadInt32(&sent)), loaded, "expected partial batch to be flushed")
+}
+
+func TestGroup_ShutdownCancelsAfterGracePeriod(t *testing.T) {
+	var sent int32
+
+	extractor := etl.NewExtractor(newInfiniteExtractor(&sent))
+	loader := etl.NewLoader(extractor.OutputCh(), func(ctx context.Context, message etl.Message) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	g := etl.NewGroup(extractor, loader)
+
+	runErrCh := make(chan error, 1)
+	go func() {
+		runErrCh <- g.Run(context.Background())
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	err := g.Shutdown(10 * time.Millisecond)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Equal(t, context.Canceled, <-runErrCh)
+}
+
+func TestPipeline_DrainStopsExtractors(t *testing.T) {
+	var sent int32
+
+	l := &fakeLoader{}
+
+	p := etl.NewPipeline()
+	p.AddExtractor("src", newInfiniteExtractor(&sent))
+	p.AddLoader("sink", l.Handle)
+	p.From("src").To("sink")
+
+	runErrCh := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		runErrCh <- p.Drain(ctx)
+	}()
+
+	err := p.Run(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, <-runErrCh)
+	require.Equal(t, int(atomic.LoadInt32(&sent)), len(l.calls))
+}
+
+func TestGroup_RunsOnlyOnce(t *testing.T) {
+	l := &fakeLoader{}
+	g := etl.NewGroup(etl.NewLoader(newFilledChannel(1), l.Handle))
+
+	err := g.Run(context.Background())
+	require.NoError(t, err)
+
+	err = g.Run(context.Background())
+	require.Equal(t, etl.ErrGroupAlreadyRunning, err)
+	require.Equal(t, 1, len(l.calls))
+}
+
+func TestPipeline_RunsAgain(t *testing.T) {
+	l := &fakeLoader{}
+
+	p := etl.NewPipeline()
+	p.AddExtractor("src", newFakeExtractor(1, 2))
+	p.AddLoader("sink", l.Handle)
+	p.From("src").To("sink")
+
+	err := p.Run(context.Background())
+	require.NoError(t, err)
+
+	err = p.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{1, 2, 1, 2}, payloadsOf(l))
+}
@@ -2,6 +2,7 @@ package etl
 
 import (
 	"context"
+	"sync"
 )
 
 type Sender interface {
@@ -17,17 +18,27 @@ type Sender interface {
 
 type senderOnCompleteHook func(ctx context.Context, outMsg Message, outChNr uint) error
 
+// senderTracker attaches acknowledgement tracking to a message before it's sent
+type senderTracker func(msg Message) Message
+
+// senderEmitter replaces sending messages to output channels, e.g. to buffer them
+type senderEmitter func(ctx context.Context, outChNr uint, msg Message) error
+
 type sender struct {
 	outputChs      []chan Message
 	newMessageOpts []MessageOption
 	onCompleteHook senderOnCompleteHook
+	tracker        senderTracker
+	emitter        senderEmitter
 }
 
-func newSender(outputChs []chan Message, newMessageOpts []MessageOption, onCompleteHook senderOnCompleteHook) sender {
+func newSender(outputChs []chan Message, newMessageOpts []MessageOption, onCompleteHook senderOnCompleteHook, tracker senderTracker, emitter senderEmitter) sender {
 	return sender{
 		outputChs:      outputChs,
 		newMessageOpts: newMessageOpts,
 		onCompleteHook: onCompleteHook,
+		tracker:        tracker,
+		emitter:        emitter,
 	}
 }
 
@@ -42,14 +53,16 @@ func (s sender) SendCh(ctx context.Context, channelNr uint, payload interface{})
 }
 
 func (s sender) SendMessage(ctx context.Context, msg Message) error {
-	return s.SendCh(ctx, 0, msg)
+	return s.SendChMessage(ctx, 0, msg)
 }
 
 func (s sender) SendChMessage(ctx context.Context, channelNr uint, msg Message) error {
-	if len(s.outputChs) < int(channelNr) {
+	if len(s.outputChs) <= int(channelNr) {
 		return ErrOutputMessageOutOfChannelsRange
 	}
 
+	msg = withHeaders(msg, outgoingHeadersFromContext(ctx))
+
 	if s.onCompleteHook != nil {
 		err := s.onCompleteHook(ctx, msg, channelNr)
 		if err != nil {
@@ -57,6 +70,14 @@ func (s sender) SendChMessage(ctx context.Context, channelNr uint, msg Message)
 		}
 	}
 
+	if s.tracker != nil {
+		msg = s.tracker(msg)
+	}
+
+	if s.emitter != nil {
+		return s.emitter(ctx, channelNr, msg)
+	}
+
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -65,3 +86,68 @@ func (s sender) SendChMessage(ctx context.Context, channelNr uint, msg Message)
 
 	return nil
 }
+
+// bufferedOutput is a message waiting to be sent to an output channel
+type bufferedOutput struct {
+	outChNr uint
+	msg     Message
+}
+
+// attemptSender buffers messages sent during a single handler attempt. They're sent by the stage sender only once the
+// attempt succeeds, so output of failed attempts is discarded and retries don't emit it again.
+type attemptSender struct {
+	sender
+
+	target  sender
+	mu      sync.Mutex
+	outputs []bufferedOutput
+}
+
+func newAttemptSender(target sender) *attemptSender {
+	s := &attemptSender{target: target}
+	s.sender = newSender(target.outputChs, target.newMessageOpts, nil, nil, s.emit)
+
+	return s
+}
+
+func (s *attemptSender) emit(ctx context.Context, outChNr uint, msg Message) error {
+	s.mu.Lock()
+	s.outputs = append(s.outputs, bufferedOutput{outChNr: outChNr, msg: msg})
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *attemptSender) flush(ctx context.Context) error {
+	var err error
+	for _, output := range s.outputs {
+		err = s.target.SendChMessage(ctx, output.outChNr, output.msg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runAttempts calls fn according to the retry policy. If failed calls are retried, messages sent by every attempt are
+// buffered, and only output of the successful one is sent. Returns context with number of attempts made and the last
+// error.
+func runAttempts(ctx context.Context, policy *RetryPolicy, s sender, fn func(ctx context.Context, sender Sender) error) (context.Context, error) {
+	if policy == nil {
+		return policy.run(ctx, func(ctx context.Context) error {
+			return fn(ctx, s)
+		})
+	}
+
+	var attempt *attemptSender
+	hookCtx, err := policy.run(ctx, func(ctx context.Context) error {
+		attempt = newAttemptSender(s)
+		return fn(ctx, attempt)
+	})
+	if err != nil {
+		return hookCtx, err
+	}
+
+	return hookCtx, attempt.flush(ctx)
+}
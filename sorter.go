@@ -0,0 +1,328 @@
+package etl
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"io"
+	"sort"
+	"time"
+)
+
+// MessageLessFunc reports whether message a should be sorted before message b
+type MessageLessFunc func(a, b Message) bool
+
+// Sorter sorts a finite stream of messages, sending them once input channel is closed. Sort is stable. If there are
+// more messages than the run size, sorted runs are spilled to temporary files and merged at the end. Only
+// acknowledgement of spilled messages is kept in memory, so they're acknowledged once their decoded copies are.
+type Sorter interface {
+	Runner
+	OutputCh() <-chan Message
+}
+
+type sorter struct {
+	less MessageLessFunc
+
+	inputCh  <-chan Message
+	outputCh chan Message
+	run      []Message
+	spills   []string
+	pending  map[spillPosition]ackResolver
+
+	opts *sorterOptions
+}
+
+func NewSorter(inputCh <-chan Message, less MessageLessFunc, optsSetters ...SorterOption) Sorter {
+	opts := newSorterOptions(optsSetters...)
+
+	return &sorter{
+		less: less,
+
+		inputCh:  inputCh,
+		outputCh: make(chan Message, opts.outputChannelBufferSize),
+		pending:  make(map[spillPosition]ackResolver),
+
+		opts: opts,
+	}
+}
+
+func (s *sorter) OutputCh() <-chan Message {
+	return s.outputCh
+}
+
+func (s *sorter) preRunHooks(ctx context.Context) error {
+	var err error
+	for _, hook := range s.opts.hooksPreRun {
+		err = hook(ctx, s.inputCh)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *sorter) onSpillHook(ctx context.Context, messages int, path string) error {
+	var err error
+	for _, hook := range s.opts.hooksOnSpill {
+		err = hook(ctx, messages, path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run sorts messages, until input channel is closed, and sends them. Note that execution of this function is blocking, until processing is finished.
+func (s *sorter) Run(ctx context.Context) error {
+	err := s.preRunHooks(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to run sorter preRunHooks")
+	}
+
+	defer close(s.outputCh)
+	defer func() {
+		removeSpills(s.spills)
+	}()
+
+	var (
+		inMsg Message
+		ok    bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case inMsg, ok = <-s.inputCh:
+			if !ok {
+				return s.emit(ctx)
+			}
+
+			s.run = append(s.run, inMsg)
+			if s.opts.runSize > 0 && len(s.run) >= s.opts.runSize {
+				err = s.spill(ctx)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// spill writes sorted run to a temporary file
+func (s *sorter) spill(ctx context.Context) error {
+	sort.SliceStable(s.run, func(i, j int) bool { return s.less(s.run[i], s.run[j]) })
+
+	w, err := newSpillWriter(s.opts.tempDir, "etl-sorter-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create sorter spill file")
+	}
+
+	var fields [][]byte
+	for seq, msg := range s.run {
+		fields, err = s.encode(msg, seq)
+		if err != nil {
+			_, _ = w.close()
+			return errors.Wrapf(err, "failed to encode message %q", msg.ID())
+		}
+
+		err = w.write(fields...)
+		if err != nil {
+			_, _ = w.close()
+			return errors.Wrap(err, "failed to write sorter spill file")
+		}
+	}
+
+	path, err := w.close()
+	s.spills = append(s.spills, path)
+	if err != nil {
+		return errors.Wrap(err, "failed to write sorter spill file")
+	}
+
+	err = s.onSpillHook(ctx, len(s.run), path)
+	if err != nil {
+		return errors.Wrap(err, "failed to run sorter onSpill hook")
+	}
+
+	// spilled messages are acknowledged once their decoded copies are, so only acknowledgement is kept in memory
+	run := len(s.spills) - 1
+	for seq, msg := range s.run {
+		resolve, tracked := ackResolverOf(msg)
+		if tracked {
+			s.pending[spillPosition{run: run, seq: seq}] = resolve
+		}
+	}
+	s.run = nil
+
+	return nil
+}
+
+// spillPosition identifies a message within spilled runs
+type spillPosition struct {
+	run int
+	seq int
+}
+
+const sorterSpillFields = 8
+
+// encode encodes message into fields: sequence number within the run, id, headers, time of creation, event time,
+// processing start time, watermark and payload
+func (s *sorter) encode(msg Message, seq int) ([][]byte, error) {
+	payload, err := s.opts.codec.Encode(msg.Payload())
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		headers []byte
+		buf     [binary.MaxVarintLen64]byte
+	)
+	for key, value := range msg.Headers() {
+		for _, str := range []string{key, value} {
+			headers = append(headers, buf[:binary.PutUvarint(buf[:], uint64(len(str)))]...)
+			headers = append(headers, str...)
+		}
+	}
+
+	return [][]byte{
+		buf[:binary.PutUvarint(buf[:], uint64(seq))],
+		[]byte(msg.ID()),
+		headers,
+		encodeTime(msg.CreatedAt()),
+		encodeTime(msg.EventTime()),
+		encodeTime(msg.ProcessingStartedAt()),
+		encodeTime(msg.Watermark()),
+		payload,
+	}, nil
+}
+
+func (s *sorter) decode(fields [][]byte) (Message, int, error) {
+	seq, n := binary.Uvarint(fields[0])
+	if n <= 0 {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+
+	payload, err := s.opts.codec.Decode(fields[7])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		headers = make(map[string]string)
+		data    = fields[2]
+		strs    [2]string
+	)
+	for len(data) > 0 {
+		for i := range strs {
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return nil, 0, io.ErrUnexpectedEOF
+			}
+
+			strs[i] = string(data[n : n+int(size)])
+			data = data[n+int(size):]
+		}
+		headers[strs[0]] = strs[1]
+	}
+
+	return NewMessage(
+		payload,
+		MessageWithID(string(fields[1])),
+		MessageWithHeaders(headers),
+		messageWithCreatedAt(decodeTime(fields[3])),
+		MessageWithEventTime(decodeTime(fields[4])),
+		MessageWithProcessingStartedAt(decodeTime(fields[5])),
+		MessageWithWatermark(decodeTime(fields[6])),
+	), int(seq), nil
+}
+
+// encodeTime encodes time as unix nanoseconds, or as an empty field for zero time, which doesn't fit them
+func encodeTime(t time.Time) []byte {
+	if t.IsZero() {
+		return nil
+	}
+
+	buf := make([]byte, binary.MaxVarintLen64)
+
+	return buf[:binary.PutVarint(buf, t.UnixNano())]
+}
+
+func decodeTime(data []byte) time.Time {
+	if len(data) == 0 {
+		return time.Time{}
+	}
+
+	nsec, _ := binary.Varint(data)
+
+	return time.Unix(0, nsec)
+}
+
+func (s *sorter) spillSource(run int, r *spillReader) mergeSource {
+	return func() (interface{}, bool, error) {
+		fields, err := r.read(sorterSpillFields)
+		if err == io.EOF {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, errors.Wrap(err, "failed to read sorter spill file")
+		}
+
+		msg, seq, err := s.decode(fields)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "failed to decode spilled message")
+		}
+
+		return s.withSpilledAck(msg, spillPosition{run: run, seq: seq}), true, nil
+	}
+}
+
+// withSpilledAck resolves acknowledgement of the original spilled message once its decoded copy is acknowledged
+func (s *sorter) withSpilledAck(msg Message, pos spillPosition) Message {
+	resolve, ok := s.pending[pos]
+	if !ok {
+		return msg
+	}
+	delete(s.pending, pos)
+
+	return withAckTracker(msg, newAckTracker(resolve))
+}
+
+// emit merges spilled runs with the one in memory, and sends messages in order
+func (s *sorter) emit(ctx context.Context) error {
+	sort.SliceStable(s.run, func(i, j int) bool { return s.less(s.run[i], s.run[j]) })
+
+	sources := make([]mergeSource, 0, len(s.spills)+1)
+	for run, path := range s.spills {
+		r, err := newSpillReader(path)
+		if err != nil {
+			return errors.Wrap(err, "failed to open sorter spill file")
+		}
+		defer r.close()
+
+		sources = append(sources, s.spillSource(run, r))
+	}
+	run := s.run
+	sources = append(sources, func() (interface{}, bool, error) {
+		if len(run) == 0 {
+			return nil, false, nil
+		}
+
+		msg := run[0]
+		run = run[1:]
+
+		return msg, true, nil
+	})
+
+	return mergeSorted(sources, func(a, b interface{}) bool {
+		return s.less(a.(Message), b.(Message))
+	}, func(v interface{}) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case s.outputCh <- v.(Message):
+		}
+
+		return nil
+	})
+}
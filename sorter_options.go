@@ -0,0 +1,66 @@
+package etl
+
+import (
+	"context"
+	"os"
+)
+
+type sorterOptions struct {
+	hooksPreRun  []SorterPreRunHook
+	hooksOnSpill []SorterOnSpillHook
+
+	codec                   Codec
+	tempDir                 string
+	runSize                 int
+	outputChannelBufferSize int
+}
+
+func newSorterOptions(optsSetters ...SorterOption) *sorterOptions {
+	opts := &sorterOptions{
+		codec:   GobCodec(),
+		tempDir: os.TempDir(),
+	}
+
+	for _, setter := range optsSetters {
+		if setter != nil {
+			setter(opts)
+		}
+	}
+
+	return opts
+}
+
+type SorterOption func(o *sorterOptions)
+
+type SorterPreRunHook func(ctx context.Context, inputCh <-chan Message) error
+
+func SorterWithPreRunHook(hook SorterPreRunHook) SorterOption {
+	return func(o *sorterOptions) { o.hooksPreRun = append(o.hooksPreRun, hook) }
+}
+
+// SorterOnSpillHook is called once a sorted run of messages has been spilled to a file
+type SorterOnSpillHook func(ctx context.Context, messages int, path string) error
+
+func SorterWithOnSpillHook(hook SorterOnSpillHook) SorterOption {
+	return func(o *sorterOptions) { o.hooksOnSpill = append(o.hooksOnSpill, hook) }
+}
+
+func SorterWithOutputChannelBufferSize(size int) SorterOption {
+	return func(o *sorterOptions) { o.outputChannelBufferSize = size }
+}
+
+// SorterWithRunSize sets maximum number of messages sorted in memory. Once it's exceeded, sorted run is spilled to a
+// temporary file. By default, all messages are sorted in memory
+func SorterWithRunSize(runSize int) SorterOption {
+	return func(o *sorterOptions) { o.runSize = runSize }
+}
+
+// SorterWithCodec sets codec of spilled payloads. GobCodec is used by default
+func SorterWithCodec(codec Codec) SorterOption {
+	return func(o *sorterOptions) { o.codec = codec }
+}
+
+// SorterWithTempDir sets directory of spill files. By default, os.TempDir is used
+func SorterWithTempDir(dir string) SorterOption {
+	return func(o *sorterOptions) { o.tempDir = dir }
+}
@@ -0,0 +1,196 @@
+package etl_test
+
+import (
+	"context"
+	"errors"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeEventCodec struct{}
+
+func (fakeEventCodec) Encode(v interface{}) ([]byte, error) {
+	event := v.(fakeEvent)
+	return []byte(event.user + ":" + strconv.Itoa(event.at)), nil
+}
+
+func (fakeEventCodec) Decode(data []byte) (interface{}, error) {
+	parts := strings.SplitN(string(data), ":", 2)
+
+	at, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return fakeEvent{user: parts[0], at: at}, nil
+}
+
+func fakeEventLess(a, b etl.Message) bool {
+	return a.Payload().(fakeEvent).at < b.Payload().(fakeEvent).at
+}
+
+func TestSorter_IsStable(t *testing.T) {
+	for _, runSize := range []int{0, 1, 2, 3} {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		dir := t.TempDir()
+		hook := &fakeAckHook{}
+		extractor := etl.NewExtractor(newFakeExtractor(
+			fakeEvent{user: "a", at: 3},
+			fakeEvent{user: "b", at: 1},
+			fakeEvent{user: "c", at: 3},
+			fakeEvent{user: "d", at: 2},
+			fakeEvent{user: "e", at: 1},
+		),
+			etl.ExtractorWithOnAckHook(hook.OnAck),
+			etl.ExtractorWithOnNackHook(hook.OnNack),
+		)
+		sorter := etl.NewSorter(extractor.OutputCh(), fakeEventLess,
+			etl.SorterWithRunSize(runSize),
+			etl.SorterWithTempDir(dir),
+			etl.SorterWithCodec(fakeEventCodec{}),
+		)
+		var (
+			users       []string
+			ackedBefore []int
+		)
+		loader := etl.NewLoader(sorter.OutputCh(), func(ctx context.Context, msg etl.Message) error {
+			hook.Lock()
+			ackedBefore = append(ackedBefore, len(hook.acked))
+			hook.Unlock()
+
+			users = append(users, msg.Payload().(fakeEvent).user)
+			return nil
+		})
+
+		err := etl.RunAll(ctx, extractor, sorter, loader)
+		require.NoError(t, err)
+		require.Equal(t, []string{"b", "e", "d", "a", "c"}, users, "run size %d", runSize)
+		// every message is acknowledged only after it's loaded
+		require.Equal(t, []int{0, 1, 2, 3, 4}, ackedBefore, "run size %d", runSize)
+		require.Len(t, hook.acked, 5, "run size %d", runSize)
+
+		files, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Empty(t, files, "expected spill files to be removed")
+
+		cancel()
+	}
+}
+
+func TestSorter_SpillPreservesMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	second := etl.NewMessage(2,
+		etl.MessageWithID("second"),
+		etl.MessageWithHeader("tenant", "a"),
+		etl.MessageWithEventTime(time.Unix(10, 0)),
+		etl.MessageWithWatermark(time.Unix(5, 0)),
+	)
+	inputCh := make(chan etl.Message, 2)
+	inputCh <- second
+	inputCh <- etl.NewMessage(1, etl.MessageWithID("first"))
+	close(inputCh)
+
+	var spills int
+	sorter := etl.NewSorter(inputCh, func(a, b etl.Message) bool { return a.Payload().(int) < b.Payload().(int) },
+		etl.SorterWithRunSize(1),
+		etl.SorterWithTempDir(t.TempDir()),
+		etl.SorterWithOnSpillHook(func(ctx context.Context, messages int, path string) error {
+			spills++
+			return nil
+		}),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(sorter.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, sorter, loader)
+	require.NoError(t, err)
+	require.Equal(t, 2, spills)
+	require.Equal(t, []interface{}{1, 2}, payloadsOf(l))
+	require.Equal(t, "first", l.calls[0].ID())
+	require.Equal(t, "second", l.calls[1].ID())
+	require.Equal(t, "a", l.calls[1].Header("tenant"))
+	require.Equal(t, time.Unix(10, 0), l.calls[1].EventTime())
+	require.Equal(t, time.Unix(5, 0), l.calls[1].Watermark())
+	require.True(t, l.calls[0].Watermark().IsZero())
+	require.True(t, second.CreatedAt().Equal(l.calls[1].CreatedAt()))
+}
+
+func TestSorter_NacksSpilledMessagesOnceLoadFails(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errTest := errors.New("test")
+	hook := &fakeAckHook{}
+	extractor := etl.NewExtractor(newFakeExtractor(
+		fakeEvent{user: "a", at: 2},
+		fakeEvent{user: "b", at: 1},
+	),
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	sorter := etl.NewSorter(extractor.OutputCh(), fakeEventLess,
+		etl.SorterWithRunSize(1),
+		etl.SorterWithTempDir(t.TempDir()),
+		etl.SorterWithCodec(fakeEventCodec{}),
+	)
+	loader := etl.NewLoader(sorter.OutputCh(), func(ctx context.Context, msg etl.Message) error {
+		if msg.Payload().(fakeEvent).user == "a" {
+			return errTest
+		}
+
+		return nil
+	}, etl.LoaderWithFailOnError(false))
+
+	err := etl.RunAll(ctx, extractor, sorter, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{fakeEvent{user: "b", at: 1}}, hook.acked)
+	require.Equal(t, []interface{}{fakeEvent{user: "a", at: 2}}, hook.nacked)
+	require.Equal(t, []error{errTest}, hook.errs)
+}
+
+func TestSorter_AcksSpilledMessagesWithRepeatedIDs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errTest := errors.New("test")
+	hook := &fakeAckHook{}
+	extractor := etl.NewExtractor(func(ctx context.Context, sender etl.Sender) error {
+		// redelivered messages share their id
+		for _, event := range []fakeEvent{{user: "a", at: 1}, {user: "a", at: 5}, {user: "b", at: 2}, {user: "c", at: 3}} {
+			err := sender.SendMessage(ctx, etl.NewMessage(event, etl.MessageWithID("redelivered")))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	sorter := etl.NewSorter(extractor.OutputCh(), fakeEventLess,
+		etl.SorterWithRunSize(2),
+		etl.SorterWithTempDir(t.TempDir()),
+		etl.SorterWithCodec(fakeEventCodec{}),
+	)
+	loader := etl.NewLoader(sorter.OutputCh(), func(ctx context.Context, msg etl.Message) error {
+		if msg.Payload().(fakeEvent).user == "b" {
+			return errTest
+		}
+
+		return nil
+	}, etl.LoaderWithFailOnError(false))
+
+	err := etl.RunAll(ctx, extractor, sorter, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{fakeEvent{user: "a", at: 1}, fakeEvent{user: "c", at: 3}, fakeEvent{user: "a", at: 5}}, hook.acked)
+	require.Equal(t, []interface{}{fakeEvent{user: "b", at: 2}}, hook.nacked)
+}
@@ -0,0 +1,182 @@
+package etl
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+// spillWriter writes records made of a fixed number of fields to a temporary file
+type spillWriter struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+func newSpillWriter(dir, pattern string) (*spillWriter, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return &spillWriter{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (s *spillWriter) write(fields ...[]byte) error {
+	var buf [binary.MaxVarintLen64]byte
+	for _, field := range fields {
+		n := binary.PutUvarint(buf[:], uint64(len(field)))
+
+		_, err := s.w.Write(buf[:n])
+		if err != nil {
+			return err
+		}
+
+		_, err = s.w.Write(field)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// close flushes and closes the file, returning its path
+func (s *spillWriter) close() (string, error) {
+	err := s.w.Flush()
+	if err != nil {
+		_ = s.f.Close()
+		return s.f.Name(), err
+	}
+
+	return s.f.Name(), s.f.Close()
+}
+
+type spillReader struct {
+	f *os.File
+	r *bufio.Reader
+}
+
+func newSpillReader(path string) (*spillReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &spillReader{f: f, r: bufio.NewReader(f)}, nil
+}
+
+// read reads a record of n fields. Returns io.EOF once there are no more records
+func (s *spillReader) read(n int) ([][]byte, error) {
+	fields := make([][]byte, n)
+	for i := range fields {
+		size, err := binary.ReadUvarint(s.r)
+		if err == io.EOF && i > 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		fields[i] = make([]byte, size)
+
+		_, err = io.ReadFull(s.r, fields[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return fields, nil
+}
+
+func (s *spillReader) close() error {
+	return s.f.Close()
+}
+
+func removeSpills(paths []string) {
+	for _, path := range paths {
+		_ = os.Remove(path)
+	}
+}
+
+// mergeSource yields sorted values. Returns false once there are no more values
+type mergeSource func() (interface{}, bool, error)
+
+type mergeCursor struct {
+	head   interface{}
+	source int
+	next   mergeSource
+}
+
+// mergeHeap orders heads of sorted sources, so values can be merged in order. Values equal according to less are
+// ordered by source, so merge is stable
+type mergeHeap struct {
+	cursors []*mergeCursor
+	less    func(a, b interface{}) bool
+}
+
+func (h *mergeHeap) Len() int { return len(h.cursors) }
+
+func (h *mergeHeap) Less(i, j int) bool {
+	if h.less(h.cursors[i].head, h.cursors[j].head) {
+		return true
+	}
+
+	if h.less(h.cursors[j].head, h.cursors[i].head) {
+		return false
+	}
+
+	return h.cursors[i].source < h.cursors[j].source
+}
+
+func (h *mergeHeap) Swap(i, j int) { h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i] }
+
+func (h *mergeHeap) Push(x interface{}) { h.cursors = append(h.cursors, x.(*mergeCursor)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	cursor := h.cursors[len(h.cursors)-1]
+	h.cursors = h.cursors[:len(h.cursors)-1]
+
+	return cursor
+}
+
+// mergeSorted calls fn with values of all sources in order defined by less
+func mergeSorted(sources []mergeSource, less func(a, b interface{}) bool, fn func(v interface{}) error) error {
+	h := &mergeHeap{less: less}
+	for i, next := range sources {
+		v, ok, err := next()
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			h.cursors = append(h.cursors, &mergeCursor{head: v, source: i, next: next})
+		}
+	}
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		cursor := h.cursors[0]
+
+		err := fn(cursor.head)
+		if err != nil {
+			return err
+		}
+
+		v, ok, err := cursor.next()
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			heap.Pop(h)
+			continue
+		}
+
+		cursor.head = v
+		heap.Fix(h, 0)
+	}
+
+	return nil
+}
@@ -0,0 +1,15 @@
+package etl
+
+import (
+	"context"
+	"time"
+)
+
+// contextWithHandlerTimeout derives context for a single handler call. Zero timeout means no deadline.
+func contextWithHandlerTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,80 @@
+package etl_test
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func blockUntilDone(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestHandlerTimeout_LoaderReportsDeadlineExceeded(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hook := fakeLoaderHook{}
+
+	extractor := etl.NewExtractor(newFakeExtractor(1, 2))
+	loader := etl.NewLoader(extractor.OutputCh(), func(ctx context.Context, message etl.Message) error {
+		if message.Payload() == 1 {
+			return blockUntilDone(ctx)
+		}
+
+		return nil
+	},
+		etl.LoaderWithHandlerTimeout(10*time.Millisecond),
+		etl.LoaderWithFailOnError(false),
+		etl.LoaderWithOnErrorHook(hook.OnError),
+		etl.LoaderWithOnCompleteHook(hook.OnComplete),
+	)
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.NoError(t, err)
+	require.Equal(t, []error{context.DeadlineExceeded}, hook.onErrorCalls)
+	require.Equal(t, 1, len(hook.onCompleteCalls))
+}
+
+func TestHandlerTimeout_TransformerRetriesTimedOutCalls(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls int
+
+	extractor := etl.NewExtractor(newFakeExtractor(1))
+	transformer := etl.NewTransformer(extractor.OutputCh(), func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+		calls++
+		if calls == 1 {
+			return blockUntilDone(ctx)
+		}
+
+		return sender.SendMessage(ctx, inMsg)
+	},
+		etl.TransformerWithHandlerTimeout(10*time.Millisecond),
+		etl.TransformerWithRetry(etl.RetryPolicy{MaxAttempts: 2}),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(transformer.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor, transformer, loader)
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+	require.Equal(t, 1, len(l.calls))
+}
+
+func TestHandlerTimeout_LoaderBatchedFailsOnTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	extractor := etl.NewExtractor(newFakeExtractor(1))
+	loader := etl.NewLoaderBatched(extractor.OutputCh(), func(ctx context.Context, messages []etl.Message) error {
+		return blockUntilDone(ctx)
+	}, etl.LoaderBatchedWithHandlerTimeout(10*time.Millisecond))
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
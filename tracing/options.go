@@ -0,0 +1,39 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+}
+
+func newOptions(optsSetters ...Option) *options {
+	opts := &options{
+		tracerProvider: otel.GetTracerProvider(),
+		propagator:     propagation.TraceContext{},
+	}
+
+	for _, setter := range optsSetters {
+		if setter != nil {
+			setter(opts)
+		}
+	}
+
+	return opts
+}
+
+type Option func(o *options)
+
+// WithTracerProvider sets provider of tracers creating spans, the global one by default
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *options) { o.tracerProvider = provider }
+}
+
+// WithPropagator sets how trace context is carried in message headers, W3C Trace Context by default
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(o *options) { o.propagator = propagator }
+}
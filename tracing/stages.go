@@ -0,0 +1,132 @@
+package tracing
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Extractor creates a span for every message sent by extractor, and carries it to the next stage
+func (t *Tracer) Extractor(stage string) etl.ExtractorOption {
+	return etl.ExtractorWithMiddleware(func(next etl.ExtractorHandler) etl.ExtractorHandler {
+		return func(ctx context.Context, sender etl.Sender) error {
+			return next(ctx, &tracingSender{sender: sender, tracer: t, stage: stage})
+		}
+	})
+}
+
+// Transformer creates a span for every handler attempt, child of the span carried by input message. Messages sent by
+// handler carry the span to the next stage.
+func (t *Tracer) Transformer(stage string) etl.TransformerOption {
+	return etl.TransformerWithMiddleware(func(next etl.TransformerHandler) etl.TransformerHandler {
+		return func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+			ctx, span := t.start(t.extract(ctx, inMsg), stage+" process", trace.SpanKindConsumer, []attribute.KeyValue{
+				stageKey.String(stage),
+				messageIDKey.String(inMsg.ID()),
+				attemptKey.Int(etl.AttemptFromContext(ctx)),
+			})
+
+			return end(span, next(t.inject(ctx), inMsg, sender))
+		}
+	})
+}
+
+// Loader creates a span for every handler attempt, child of the span carried by the message
+func (t *Tracer) Loader(stage string) etl.LoaderOption {
+	return etl.LoaderWithMiddleware(func(next etl.LoaderHandler) etl.LoaderHandler {
+		return func(ctx context.Context, message etl.Message) error {
+			ctx, span := t.start(t.extract(ctx, message), stage+" process", trace.SpanKindConsumer, []attribute.KeyValue{
+				stageKey.String(stage),
+				messageIDKey.String(message.ID()),
+				attemptKey.Int(etl.AttemptFromContext(ctx)),
+			})
+
+			return end(span, next(ctx, message))
+		}
+	})
+}
+
+// TransformerBatched creates a span for every handler attempt, linked to spans carried by all messages of the batch.
+// Messages sent by handler carry the span to the next stage.
+func (t *Tracer) TransformerBatched(stage string) etl.TransformerBatchedOption {
+	return etl.TransformerBatchedWithMiddleware(func(next etl.TransformerBatchedHandler) etl.TransformerBatchedHandler {
+		return func(ctx context.Context, inMsgs []etl.Message, sender etl.Sender) error {
+			ctx, span := t.startBatch(ctx, stage, inMsgs)
+
+			return end(span, next(t.inject(ctx), inMsgs, sender))
+		}
+	})
+}
+
+// LoaderBatched creates a span for every handler attempt, linked to spans carried by all messages of the batch
+func (t *Tracer) LoaderBatched(stage string) etl.LoaderBatchedOption {
+	return etl.LoaderBatchedWithMiddleware(func(next etl.LoaderBatchedHandler) etl.LoaderBatchedHandler {
+		return func(ctx context.Context, messages []etl.Message) error {
+			ctx, span := t.startBatch(ctx, stage, messages)
+
+			return end(span, next(ctx, messages))
+		}
+	})
+}
+
+// startBatch starts a span of a batch handler attempt, linked to spans carried by all messages of the batch
+func (t *Tracer) startBatch(ctx context.Context, stage string, messages []etl.Message) (context.Context, trace.Span) {
+	links := make([]trace.Link, 0, len(messages))
+	for _, msg := range messages {
+		spanCtx := trace.SpanContextFromContext(t.extract(context.Background(), msg))
+		if spanCtx.IsValid() {
+			links = append(links, trace.Link{
+				SpanContext: spanCtx,
+				Attributes:  []attribute.KeyValue{messageIDKey.String(msg.ID())},
+			})
+		}
+	}
+
+	return t.start(ctx, stage+" process batch", trace.SpanKindConsumer, []attribute.KeyValue{
+		stageKey.String(stage),
+		batchSizeKey.Int(len(messages)),
+		attemptKey.Int(etl.AttemptFromContext(ctx)),
+	}, links...)
+}
+
+// tracingSender creates a span for every sent message, and carries it in message headers
+type tracingSender struct {
+	sender etl.Sender
+	tracer *Tracer
+	stage  string
+}
+
+func (s *tracingSender) send(ctx context.Context, outChNr uint, msg etl.Message, fn func(ctx context.Context) error) error {
+	attrs := []attribute.KeyValue{
+		stageKey.String(s.stage),
+		outputChNrKey.Int(int(outChNr)),
+	}
+	if msg != nil {
+		attrs = append(attrs, messageIDKey.String(msg.ID()))
+	}
+
+	ctx, span := s.tracer.start(ctx, s.stage+" send", trace.SpanKindProducer, attrs)
+
+	return end(span, fn(s.tracer.inject(ctx)))
+}
+
+func (s *tracingSender) Send(ctx context.Context, payload interface{}) error {
+	return s.SendCh(ctx, 0, payload)
+}
+
+func (s *tracingSender) SendCh(ctx context.Context, outChNr uint, payload interface{}) error {
+	return s.send(ctx, outChNr, nil, func(ctx context.Context) error {
+		return s.sender.SendCh(ctx, outChNr, payload)
+	})
+}
+
+func (s *tracingSender) SendMessage(ctx context.Context, message etl.Message) error {
+	return s.SendChMessage(ctx, 0, message)
+}
+
+func (s *tracingSender) SendChMessage(ctx context.Context, outChNr uint, message etl.Message) error {
+	return s.send(ctx, outChNr, message, func(ctx context.Context) error {
+		return s.sender.SendChMessage(ctx, outChNr, message)
+	})
+}
@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/damian-szulc/go-etl/tracing"
+
+const (
+	stageKey      = attribute.Key("etl.stage")
+	messageIDKey  = attribute.Key("etl.message.id")
+	attemptKey    = attribute.Key("etl.attempt")
+	batchSizeKey  = attribute.Key("etl.batch.size")
+	outputChNrKey = attribute.Key("etl.output_channel")
+)
+
+// Tracer instruments stages with OpenTelemetry spans. Trace context is carried between stages in message headers and
+// is available in handler contexts.
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+func NewTracer(optsSetters ...Option) *Tracer {
+	opts := newOptions(optsSetters...)
+
+	return &Tracer{
+		tracer:     opts.tracerProvider.Tracer(instrumentationName),
+		propagator: opts.propagator,
+	}
+}
+
+// extract returns context with trace context carried by message headers
+func (t *Tracer) extract(ctx context.Context, msg etl.Message) context.Context {
+	return t.propagator.Extract(ctx, propagation.MapCarrier(msg.Headers()))
+}
+
+// inject returns context, which adds its trace context to headers of messages sent with it
+func (t *Tracer) inject(ctx context.Context) context.Context {
+	carrier := propagation.MapCarrier{}
+	t.propagator.Inject(ctx, carrier)
+
+	return etl.ContextWithOutgoingHeaders(ctx, carrier)
+}
+
+func (t *Tracer) start(ctx context.Context, name string, kind trace.SpanKind, attrs []attribute.KeyValue, links ...trace.Link) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...), trace.WithLinks(links...))
+}
+
+// end records result of an operation and ends the span
+func end(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+
+	return err
+}
@@ -0,0 +1,177 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"github.com/damian-szulc/go-etl"
+	"github.com/damian-szulc/go-etl/tracing"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+func newTestTracer() (*tracing.Tracer, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	return tracing.NewTracer(tracing.WithTracerProvider(provider)), exporter
+}
+
+func spansNamed(spans tracetest.SpanStubs, name string) tracetest.SpanStubs {
+	var named tracetest.SpanStubs
+	for _, span := range spans {
+		if span.Name == name {
+			named = append(named, span)
+		}
+	}
+
+	return named
+}
+
+func newExtractor(tracer *tracing.Tracer, payload ...interface{}) etl.Extractor {
+	return etl.NewExtractor(func(ctx context.Context, sender etl.Sender) error {
+		for _, p := range payload {
+			err := sender.Send(ctx, p)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, tracer.Extractor("extract"))
+}
+
+func TestTracer_CarriesSpansBetweenStages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tracer, exporter := newTestTracer()
+
+	extractor := newExtractor(tracer, 1, 2, 3)
+	transformer := etl.NewTransformer(extractor.OutputCh(), func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+		return sender.Send(ctx, inMsg.Payload())
+	}, tracer.Transformer("transform"))
+
+	var handlerSpans []trace.SpanContext
+	loader := etl.NewLoader(transformer.OutputCh(), func(ctx context.Context, message etl.Message) error {
+		handlerSpans = append(handlerSpans, trace.SpanContextFromContext(ctx))
+		return nil
+	}, tracer.Loader("load"))
+
+	err := etl.RunAll(ctx, extractor, transformer, loader)
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	sends := spansNamed(spans, "extract send")
+	processes := spansNamed(spans, "transform process")
+	loads := spansNamed(spans, "load process")
+	require.Len(t, sends, 3)
+	require.Len(t, processes, 3)
+	require.Len(t, loads, 3)
+
+	for i := range sends {
+		require.Equal(t, trace.SpanKindProducer, sends[i].SpanKind)
+		require.False(t, sends[i].Parent.IsValid())
+
+		// every message is traced by a single trace, from extractor to loader
+		require.Equal(t, sends[i].SpanContext.SpanID(), processes[i].Parent.SpanID())
+		require.Equal(t, sends[i].SpanContext.TraceID(), processes[i].SpanContext.TraceID())
+		require.Equal(t, processes[i].SpanContext.SpanID(), loads[i].Parent.SpanID())
+		require.Equal(t, sends[i].SpanContext.TraceID(), loads[i].SpanContext.TraceID())
+
+		require.Equal(t, loads[i].SpanContext.SpanID(), handlerSpans[i].SpanID())
+	}
+}
+
+func TestTracer_LinksMessagesOfBatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tracer, exporter := newTestTracer()
+
+	extractor := newExtractor(tracer, 1, 2, 3)
+	loader := etl.NewLoaderBatched(extractor.OutputCh(), func(ctx context.Context, messages []etl.Message) error {
+		return nil
+	}, tracer.LoaderBatched("load"), etl.LoaderBatchedWithFixedSizeBatches(10))
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	sends := spansNamed(spans, "extract send")
+	batches := spansNamed(spans, "load process batch")
+	require.Len(t, sends, 3)
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0].Links, 3)
+
+	for i, link := range batches[0].Links {
+		require.Equal(t, sends[i].SpanContext.SpanID(), link.SpanContext.SpanID())
+		require.Equal(t, sends[i].SpanContext.TraceID(), link.SpanContext.TraceID())
+	}
+}
+
+func TestTracer_CarriesBatchSpanFromBatchedTransformer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tracer, exporter := newTestTracer()
+
+	extractor := newExtractor(tracer, 1, 2)
+	transformer := etl.NewTransformerBatched(extractor.OutputCh(), func(ctx context.Context, inMsgs []etl.Message, sender etl.Sender) error {
+		return sender.Send(ctx, len(inMsgs))
+	}, tracer.TransformerBatched("transform"), etl.TransformerBatchedWithFixedSizeBatches(10))
+	loader := etl.NewLoader(transformer.OutputCh(), func(ctx context.Context, message etl.Message) error {
+		return nil
+	}, tracer.Loader("load"))
+
+	err := etl.RunAll(ctx, extractor, transformer, loader)
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	sends := spansNamed(spans, "extract send")
+	batches := spansNamed(spans, "transform process batch")
+	loads := spansNamed(spans, "load process")
+	require.Len(t, sends, 2)
+	require.Len(t, batches, 1)
+	require.Len(t, loads, 1)
+
+	require.Len(t, batches[0].Links, 2)
+	for i, link := range batches[0].Links {
+		require.Equal(t, sends[i].SpanContext.SpanID(), link.SpanContext.SpanID())
+	}
+
+	// message sent for the batch carries its span
+	require.Equal(t, batches[0].SpanContext.SpanID(), loads[0].Parent.SpanID())
+	require.Equal(t, batches[0].SpanContext.TraceID(), loads[0].SpanContext.TraceID())
+}
+
+func TestTracer_RecordsErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tracer, exporter := newTestTracer()
+
+	errLoad := errors.New("load failed")
+	extractor := newExtractor(tracer, 1)
+	loader := etl.NewLoader(extractor.OutputCh(), func(ctx context.Context, message etl.Message) error {
+		return errLoad
+	},
+		tracer.Loader("load"),
+		etl.LoaderWithFailOnError(false),
+		etl.LoaderWithRetry(etl.RetryPolicy{MaxAttempts: 2}),
+	)
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.NoError(t, err)
+
+	loads := spansNamed(exporter.GetSpans(), "load process")
+	require.Len(t, loads, 2)
+	for _, span := range loads {
+		require.Equal(t, codes.Error, span.Status.Code)
+		require.Equal(t, errLoad.Error(), span.Status.Description)
+		require.Len(t, span.Events, 1)
+	}
+}
@@ -0,0 +1,257 @@
+package etl
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"time"
+)
+
+type TransformerBatchedHandler func(ctx context.Context, inMsgs []Message, sender Sender) error
+
+// TransformerBatched processes batches of messages, e.g. to call a bulk API. Input messages are acknowledged once
+// all messages sent for their batch are acknowledged. Sent messages carry headers of all input messages, and the
+// latest of their event times.
+type TransformerBatched interface {
+	Runner
+	OutputCh() <-chan Message
+}
+
+type transformerBatched struct {
+	handler TransformerBatchedHandler
+
+	inputCh  <-chan Message
+	outputCh chan Message
+
+	opts *transformerBatchedOptions
+}
+
+func NewTransformerBatched(inputCh <-chan Message, handler TransformerBatchedHandler, optsSetters ...TransformerBatchedOption) TransformerBatched {
+	opts := newTransformerBatchedOptions(optsSetters...)
+
+	return &transformerBatched{
+		handler: wrapTransformerBatchedHandler(handler, opts.middlewares),
+
+		inputCh:  inputCh,
+		outputCh: make(chan Message, opts.outputChannelBufferSize),
+
+		opts: opts,
+	}
+}
+
+func (t *transformerBatched) OutputCh() <-chan Message {
+	return t.outputCh
+}
+
+func (t *transformerBatched) preRunHooks(ctx context.Context) error {
+	var err error
+	for _, hook := range t.opts.hooksPreRun {
+		err = hook(ctx, t.inputCh)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *transformerBatched) Run(ctx context.Context) error {
+	err := t.preRunHooks(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to run batched transformer preRunHooks")
+	}
+
+	defer close(t.outputCh)
+
+	if t.opts.partitionKey != nil {
+		return t.runPartitioned(ctx)
+	}
+
+	if t.opts.concurrency == 1 {
+		return t.runWorker(ctx, t.inputCh)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < t.opts.concurrency; i++ {
+		g.Go(func() error {
+			return t.runWorker(ctx, t.inputCh)
+		})
+	}
+
+	return g.Wait()
+}
+
+// runPartitioned dispatches messages with the same partition key to the same worker
+func (t *transformerBatched) runPartitioned(ctx context.Context) error {
+	partitionChs, inputChs := newPartitionChannels(t.opts.concurrency)
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return partitionMessages(ctx, t.inputCh, t.opts.partitionKey, partitionChs)
+	})
+	for _, inputCh := range inputChs {
+		inputCh := inputCh
+		g.Go(func() error {
+			return t.runWorker(ctx, inputCh)
+		})
+	}
+
+	return g.Wait()
+}
+
+func (t *transformerBatched) onErrorHook(ctx context.Context, inMsgs []Message, opErr error) error {
+	var err error
+	for _, hook := range t.opts.hooksOnError {
+		err = hook(ctx, inMsgs, opErr)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newAckTracker creates a tracker that acknowledges all input messages once all messages derived from the batch are
+// acknowledged. Tracker is held until handler returns, so input messages are not acknowledged while they're still
+// being processed.
+func (t *transformerBatched) newAckTracker(inMsgs []Message) *ackTracker {
+	tracker := newAckTracker(func(ctx context.Context, err error) error {
+		if err != nil {
+			return nackMessages(ctx, inMsgs, err)
+		}
+
+		return ackMessages(ctx, inMsgs)
+	})
+	tracker.add()
+
+	return tracker
+}
+
+// newTransformerSender creates a sender of messages derived from the batch. Headers of earlier input messages take
+// precedence over the later ones.
+func (t *transformerBatched) newTransformerSender(inMsgs []Message, tracker *ackTracker) sender {
+	var (
+		watermark = maxWatermark(inMsgs)
+		opts      = []MessageOption{MessageWithProcessingStartedAt(inMsgs[0].ProcessingStartedAt())}
+		eventTime time.Time
+	)
+	for i := len(inMsgs) - 1; i >= 0; i-- {
+		opts = append(opts, MessageWithHeaders(inMsgs[i].Headers()))
+		if inMsgs[i].EventTime().After(eventTime) {
+			eventTime = inMsgs[i].EventTime()
+		}
+	}
+	opts = append(opts, MessageWithEventTime(eventTime))
+
+	return newSender(
+		[]chan Message{t.outputCh},
+		opts,
+		func(ctx context.Context, outMsg Message, _ uint) error {
+			var err error
+			for _, hook := range t.opts.hooksOnComplete {
+				err = hook(ctx, inMsgs, outMsg)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		func(msg Message) Message {
+			return withAckTracker(withWatermark(msg, watermark), tracker)
+		},
+		nil,
+	)
+}
+
+func (t *transformerBatched) runWorker(ctx context.Context, inputCh <-chan Message) error {
+	var (
+		inMsgs []Message
+		err    error
+	)
+	for {
+		err = callRecovering(func() error {
+			inMsgs, err = t.opts.batcher(ctx, inputCh)
+			return err
+		})
+		if panicErr, ok := err.(*PanicError); ok {
+			// messages collected by batcher are lost, so there's nothing to retry or acknowledge
+			err = t.onErrorHook(ctx, nil, panicErr)
+			if err != nil {
+				return errors.Wrap(err, "running batched transformer on error hook has failed")
+			}
+
+			if t.opts.failOnErr {
+				return panicErr
+			}
+
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(inMsgs) == 0 {
+			return nil
+		}
+
+		err = t.process(ctx, inMsgs)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// process runs handler for a single batch. Returns an error only if processing should be stopped.
+func (t *transformerBatched) process(ctx context.Context, inMsgs []Message) error {
+	var (
+		tracker = t.newAckTracker(inMsgs)
+		sender  = t.newTransformerSender(inMsgs, tracker)
+
+		hookCtx context.Context
+		opErr   error
+		err     error
+
+		deadLettered bool
+	)
+
+	hookCtx, opErr = runAttempts(ctx, t.opts.retryPolicy, sender, func(ctx context.Context, sender Sender) error {
+		ctx, cancel := contextWithHandlerTimeout(ctx, t.opts.handlerTimeout)
+		defer cancel()
+
+		return callRecovering(func() error {
+			return t.handler(ctx, inMsgs, sender)
+		}, inMsgs...)
+	})
+	if opErr != nil {
+		err = t.onErrorHook(hookCtx, inMsgs, opErr)
+		if err != nil {
+			return errors.Wrap(err, "running batched transformer on error hook has failed")
+		}
+
+		deadLettered, err = sendToDeadLetterSink(hookCtx, t.opts.deadLetterSink, t.opts.name, inMsgs, opErr)
+		if err != nil {
+			return errors.Wrap(err, "failed to send batched transformer messages to dead letter sink")
+		}
+
+		if !deadLettered {
+			err = tracker.release(ctx, opErr)
+			if err != nil {
+				return errors.Wrap(err, "failed to nack batched transformer input messages")
+			}
+		}
+
+		if opErr == ErrCastingFailed || t.opts.failOnErr {
+			return opErr
+		}
+
+		return nil
+	}
+
+	err = tracker.release(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to ack batched transformer input messages")
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package etl
+
+import (
+	"context"
+	"time"
+)
+
+type transformerBatchedOptions struct {
+	hooksPreRun     []TransformerBatchedPreRunHook
+	hooksOnError    []TransformerBatchedOnErrorHook
+	hooksOnComplete []TransformerBatchedOnComplete
+	middlewares     []TransformerBatchedMiddleware
+	batcher         LoaderBatcher
+
+	retryPolicy    *RetryPolicy
+	deadLetterSink DeadLetterSink
+	handlerTimeout time.Duration
+
+	name                    string
+	outputChannelBufferSize int
+	concurrency             int
+	failOnErr               bool
+	partitionKey            PartitionKeyFunc
+}
+
+func newTransformerBatchedOptions(optsSetters ...TransformerBatchedOption) *transformerBatchedOptions {
+	opts := &transformerBatchedOptions{
+		batcher:     defaultLoaderBatcher,
+		concurrency: 1,
+		failOnErr:   true,
+	}
+
+	for _, setter := range optsSetters {
+		if setter != nil {
+			setter(opts)
+		}
+	}
+
+	return opts
+}
+
+type TransformerBatchedOption func(o *transformerBatchedOptions)
+
+// TransformerBatchedWithName sets name of the stage, used to describe it e.g. in dead letters
+func TransformerBatchedWithName(name string) TransformerBatchedOption {
+	return func(o *transformerBatchedOptions) { o.name = name }
+}
+
+type TransformerBatchedPreRunHook func(ctx context.Context, inputCh <-chan Message) error
+
+func TransformerBatchedWithPreRunHook(hook TransformerBatchedPreRunHook) TransformerBatchedOption {
+	return func(o *transformerBatchedOptions) { o.hooksPreRun = append(o.hooksPreRun, hook) }
+}
+
+type TransformerBatchedOnErrorHook func(ctx context.Context, inMsgs []Message, err error) error
+
+func TransformerBatchedWithOnErrorHook(hook TransformerBatchedOnErrorHook) TransformerBatchedOption {
+	return func(o *transformerBatchedOptions) { o.hooksOnError = append(o.hooksOnError, hook) }
+}
+
+type TransformerBatchedOnComplete func(ctx context.Context, inMsgs []Message, outMsg Message) error
+
+func TransformerBatchedWithOnCompleteHook(hook TransformerBatchedOnComplete) TransformerBatchedOption {
+	return func(o *transformerBatchedOptions) { o.hooksOnComplete = append(o.hooksOnComplete, hook) }
+}
+
+// TransformerBatchedWithMiddleware wraps handler with a middleware. The first middleware is the outermost one.
+func TransformerBatchedWithMiddleware(middleware TransformerBatchedMiddleware) TransformerBatchedOption {
+	return func(o *transformerBatchedOptions) { o.middlewares = append(o.middlewares, middleware) }
+}
+
+// TransformerBatchedWithOptions applies several options at once, e.g. instrumentation consisting of a middleware and
+// hooks
+func TransformerBatchedWithOptions(optsSetters ...TransformerBatchedOption) TransformerBatchedOption {
+	return func(o *transformerBatchedOptions) {
+		for _, setter := range optsSetters {
+			if setter != nil {
+				setter(o)
+			}
+		}
+	}
+}
+
+func TransformerBatchedWithOutputChannelBufferSize(size int) TransformerBatchedOption {
+	return func(o *transformerBatchedOptions) { o.outputChannelBufferSize = size }
+}
+
+// TransformerBatchedWithConcurrency sets number of workers. Values lower than 1 are treated as 1.
+func TransformerBatchedWithConcurrency(concurrency int) TransformerBatchedOption {
+	return func(o *transformerBatchedOptions) { o.concurrency = atLeastOne(concurrency) }
+}
+
+func TransformerBatchedWithFailOnError(failOnErr bool) TransformerBatchedOption {
+	return func(o *transformerBatchedOptions) { o.failOnErr = failOnErr }
+}
+
+// TransformerBatchedWithRetry retries failed handler calls according to the policy, before error hooks are called
+func TransformerBatchedWithRetry(policy RetryPolicy) TransformerBatchedOption {
+	return func(o *transformerBatchedOptions) { o.retryPolicy = &policy }
+}
+
+// TransformerBatchedWithDeadLetterSink routes messages that failed processing to the sink, after error hooks are
+// called. Every message of a failed batch is reported separately
+func TransformerBatchedWithDeadLetterSink(sink DeadLetterSink) TransformerBatchedOption {
+	return func(o *transformerBatchedOptions) { o.deadLetterSink = sink }
+}
+
+// TransformerBatchedWithHandlerTimeout limits duration of every handler call, by setting a deadline on its context.
+// Handler is expected to return once the context is done. Timed out calls are retried and reported as any other error
+func TransformerBatchedWithHandlerTimeout(timeout time.Duration) TransformerBatchedOption {
+	return func(o *transformerBatchedOptions) { o.handlerTimeout = timeout }
+}
+
+// TransformerBatchedWithPartitionKey dispatches messages with the same key to the same worker, so they are batched
+// and processed sequentially in order, while messages with different keys are processed concurrently
+func TransformerBatchedWithPartitionKey(keyFn PartitionKeyFunc) TransformerBatchedOption {
+	return func(o *transformerBatchedOptions) { o.partitionKey = keyFn }
+}
+
+func TransformerBatchedWithBatcher(batcher LoaderBatcher) TransformerBatchedOption {
+	return func(o *transformerBatchedOptions) {
+		o.batcher = batcher
+	}
+}
+
+func TransformerBatchedWithFixedSizeBatches(maxItems int) TransformerBatchedOption {
+	return TransformerBatchedWithBatcher(FixedSizeBatcher(maxItems))
+}
+
+func TransformerBatchedWithDrainedChannelBatches(maxItems int) TransformerBatchedOption {
+	return TransformerBatchedWithBatcher(DrainedChannelBatcher(maxItems))
+}
+
+func TransformerBatchedWithThrottledBatches(interval time.Duration, maxItems int) TransformerBatchedOption {
+	return TransformerBatchedWithBatcher(ThrottledBatcher(interval, maxItems))
+}
+
+func TransformerBatchedWithDebouncedBatches(interval time.Duration, maxItems int) TransformerBatchedOption {
+	return TransformerBatchedWithBatcher(DebouncedBatcher(interval, maxItems))
+}
@@ -0,0 +1,136 @@
+package etl_test
+
+import (
+	"context"
+	"errors"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func fakeSumTransformer(ctx context.Context, inMsgs []etl.Message, sender etl.Sender) error {
+	var sum int
+	for _, inMsg := range inMsgs {
+		sum += inMsg.Payload().(int)
+	}
+
+	return sender.Send(ctx, sum)
+}
+
+func TestTransformerBatched(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transformer := etl.NewTransformerBatched(newFilledChannel(1, 2, 3, 4, 5), fakeSumTransformer,
+		etl.TransformerBatchedWithFixedSizeBatches(2),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(transformer.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, transformer, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{3, 7, 5}, payloadsOf(l))
+}
+
+func TestTransformerBatched_CarriesHeadersAndEventTimeOfBatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	inputCh := make(chan etl.Message, 2)
+	inputCh <- etl.NewMessage(1,
+		etl.MessageWithHeader("tenant", "a"),
+		etl.MessageWithHeader("offset", "1"),
+		etl.MessageWithEventTime(time.Unix(20, 0)),
+	)
+	inputCh <- etl.NewMessage(2,
+		etl.MessageWithHeader("offset", "2"),
+		etl.MessageWithHeader("partition", "p"),
+		etl.MessageWithEventTime(time.Unix(10, 0)),
+	)
+	close(inputCh)
+
+	transformer := etl.NewTransformerBatched(inputCh, fakeSumTransformer, etl.TransformerBatchedWithFixedSizeBatches(2))
+	l := &fakeLoader{}
+	loader := etl.NewLoader(transformer.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, transformer, loader)
+	require.NoError(t, err)
+	require.Len(t, l.calls, 1)
+	// headers of earlier messages take precedence
+	require.Equal(t, map[string]string{"tenant": "a", "offset": "1", "partition": "p"}, l.calls[0].Headers())
+	require.Equal(t, time.Unix(20, 0), l.calls[0].EventTime())
+}
+
+func TestTransformerBatched_AcksBatchOnceOutputsAreAcked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errTest := errors.New("test")
+	hook := &fakeAckHook{}
+	extractor := etl.NewExtractor(newFakeExtractor(1, 2, 3, 4),
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	transformer := etl.NewTransformerBatched(extractor.OutputCh(), func(ctx context.Context, inMsgs []etl.Message, sender etl.Sender) error {
+		for _, inMsg := range inMsgs {
+			err := sender.Send(ctx, inMsg.Payload())
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, etl.TransformerBatchedWithFixedSizeBatches(2))
+	loader := etl.NewLoader(transformer.OutputCh(), func(ctx context.Context, msg etl.Message) error {
+		if msg.Payload() == 4 {
+			return errTest
+		}
+
+		return nil
+	}, etl.LoaderWithFailOnError(false))
+
+	err := etl.RunAll(ctx, extractor, transformer, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{1, 2}, hook.acked)
+	require.Equal(t, []interface{}{3, 4}, hook.nacked)
+}
+
+func TestTransformerBatched_FailOnError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errTest := errors.New("test")
+	var failed [][]etl.Message
+	transformer := etl.NewTransformerBatched(newFilledChannel(1, 2, 3), func(ctx context.Context, inMsgs []etl.Message, sender etl.Sender) error {
+		return errTest
+	},
+		etl.TransformerBatchedWithFixedSizeBatches(2),
+		etl.TransformerBatchedWithFailOnError(false),
+		etl.TransformerBatchedWithOnErrorHook(func(ctx context.Context, inMsgs []etl.Message, err error) error {
+			failed = append(failed, inMsgs)
+			return nil
+		}),
+	)
+
+	err := transformer.Run(ctx)
+	require.NoError(t, err)
+	require.Len(t, failed, 2)
+	require.Len(t, transformer.OutputCh(), 0)
+}
+
+func TestPipeline_TransformerBatched(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l := &fakeLoader{}
+	p := etl.NewPipeline().
+		AddExtractor("src", newFakeExtractor(1, 2, 3)).
+		AddTransformerBatched("sum", fakeSumTransformer, etl.TransformerBatchedWithFixedSizeBatches(3)).
+		AddLoader("sink", l.Handle)
+	p.From("src").To("sum").To("sink")
+
+	err := p.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{6}, payloadsOf(l))
+}
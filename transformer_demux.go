@@ -32,7 +32,7 @@ func NewTransformerDemux(inputCh <-chan Message, handler TransformerHandler, out
 	}
 
 	return &transformerDemux{
-		handler: handler,
+		handler: wrapTransformerHandler(handler, opts.middlewares),
 
 		inputCh:     inputCh,
 		outputChsNr: outputChannelsNr,
@@ -76,12 +76,16 @@ func (t *transformerDemux) Run(ctx context.Context) error {
 
 	defer t.closeChannels(t.outputChs)
 
+	if t.opts.orderedOutput || t.opts.partitionKey != nil {
+		return t.runDispatched(ctx)
+	}
+
 	if t.opts.concurrency == 1 {
 		return t.runWorker(ctx)
 	}
 
 	g, ctx := errgroup.WithContext(ctx)
-	for i := 0; i <= t.opts.concurrency; i++ {
+	for i := 0; i < t.opts.concurrency; i++ {
 		g.Go(func() error {
 			return t.runWorker(ctx)
 		})
@@ -101,10 +105,29 @@ func (t *transformerDemux) onErrorHook(ctx context.Context, inMsg Message, opErr
 	return nil
 }
 
-func (t *transformerDemux) newTransformerSender(inMsg Message) Sender {
+// newAckTracker creates a tracker that acknowledges input message once all messages derived from it are acknowledged.
+// Tracker is held until handler returns, so input message is not acknowledged while it's still being processed.
+func (t *transformerDemux) newAckTracker(inMsg Message) *ackTracker {
+	tracker := newAckTracker(func(ctx context.Context, err error) error {
+		if err != nil {
+			return inMsg.Nack(ctx, err)
+		}
+
+		return inMsg.Ack(ctx)
+	})
+	tracker.add()
+
+	return tracker
+}
+
+func (t *transformerDemux) newTransformerSender(inMsg Message, tracker *ackTracker, emitter senderEmitter) sender {
 	return newSender(
 		t.outputChs,
-		[]MessageOption{MessageWithProcessingStartedAt(inMsg.ProcessingStartedAt())},
+		[]MessageOption{
+			MessageWithProcessingStartedAt(inMsg.ProcessingStartedAt()),
+			MessageWithHeaders(inMsg.Headers()),
+			MessageWithEventTime(inMsg.EventTime()),
+		},
 		func(ctx context.Context, outMsg Message, outChNr uint) error {
 			var err error
 			for _, hook := range t.opts.hooksOnComplete {
@@ -116,16 +139,17 @@ func (t *transformerDemux) newTransformerSender(inMsg Message) Sender {
 
 			return nil
 		},
+		func(msg Message) Message {
+			return withAckTracker(withWatermark(msg, inMsg.Watermark()), tracker)
+		},
+		emitter,
 	)
 }
 
 func (t *transformerDemux) runWorker(ctx context.Context) error {
 	var (
-		sender Sender
-
 		inMsg Message
 		ok    bool
-		opErr error
 		err   error
 	)
 	for {
@@ -137,19 +161,65 @@ func (t *transformerDemux) runWorker(ctx context.Context) error {
 				return nil
 			}
 
-			sender = t.newTransformerSender(inMsg)
+			err = t.process(ctx, inMsg, nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
 
-			opErr = t.handler(ctx, inMsg, sender)
-			if opErr != nil {
-				err = t.onErrorHook(ctx, inMsg, opErr)
-				if err != nil {
-					return errors.Wrap(err, "running on error hook has failed")
-				}
+// process runs handler for a single message. Output messages are sent to output channels, unless emitter is
+// specified. Returns an error only if processing should be stopped.
+func (t *transformerDemux) process(ctx context.Context, inMsg Message, emitter senderEmitter) error {
+	var (
+		tracker = t.newAckTracker(inMsg)
+		sender  = t.newTransformerSender(inMsg, tracker, emitter)
 
-				if opErr == ErrCastingFailed || t.opts.failOnErr {
-					return opErr
-				}
+		hookCtx context.Context
+		opErr   error
+		err     error
+
+		deadLettered bool
+	)
+
+	hookCtx, opErr = runAttempts(ctx, t.opts.retryPolicy, sender, func(ctx context.Context, sender Sender) error {
+		ctx, cancel := contextWithHandlerTimeout(ctx, t.opts.handlerTimeout)
+		defer cancel()
+
+		return callRecovering(func() error {
+			return t.handler(ctx, inMsg, sender)
+		}, inMsg)
+	})
+	if opErr != nil {
+		err = t.onErrorHook(hookCtx, inMsg, opErr)
+		if err != nil {
+			return errors.Wrap(err, "running on error hook has failed")
+		}
+
+		deadLettered, err = sendToDeadLetterSink(hookCtx, t.opts.deadLetterSink, t.opts.name, []Message{inMsg}, opErr)
+		if err != nil {
+			return errors.Wrap(err, "failed to send transformer message to dead letter sink")
+		}
+
+		if !deadLettered {
+			err = tracker.release(ctx, opErr)
+			if err != nil {
+				return errors.Wrap(err, "failed to nack transformer input message")
 			}
 		}
+
+		if opErr == ErrCastingFailed || t.opts.failOnErr {
+			return opErr
+		}
+
+		return nil
 	}
+
+	err = tracker.release(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to ack transformer input message")
+	}
+
+	return nil
 }
@@ -0,0 +1,190 @@
+package etl
+
+import (
+	"context"
+	"golang.org/x/sync/errgroup"
+	"sync"
+)
+
+// transformerJob is a message dispatched to a worker. Slot is set if output has to be ordered.
+type transformerJob struct {
+	msg  Message
+	slot *orderedSlot
+}
+
+// orderedSlot collects output messages of a single input message, so they can be emitted in order of input messages
+type orderedSlot struct {
+	sync.Mutex
+	outputs []bufferedOutput
+	doneCh  chan struct{}
+}
+
+func newOrderedSlot() *orderedSlot {
+	return &orderedSlot{doneCh: make(chan struct{})}
+}
+
+func (s *orderedSlot) emit(ctx context.Context, outChNr uint, msg Message) error {
+	s.Lock()
+	s.outputs = append(s.outputs, bufferedOutput{outChNr: outChNr, msg: msg})
+	s.Unlock()
+
+	return nil
+}
+
+func (s *orderedSlot) done() {
+	close(s.doneCh)
+}
+
+// runDispatched dispatches messages to workers, instead of workers reading the input channel directly. Messages are
+// dispatched by partition key if it's set, so messages with the same key are processed sequentially by the same
+// worker. If output has to be ordered, output messages are emitted in order of input messages and number of input
+// messages waiting to be emitted is limited by the reorder window.
+func (t *transformerDemux) runDispatched(ctx context.Context) error {
+	var (
+		jobsChs []chan transformerJob
+		slotsCh chan *orderedSlot
+	)
+
+	if t.opts.partitionKey != nil {
+		jobsChs = make([]chan transformerJob, t.opts.concurrency)
+		for i := range jobsChs {
+			jobsChs[i] = make(chan transformerJob)
+		}
+	} else {
+		jobsChs = []chan transformerJob{make(chan transformerJob)}
+	}
+
+	if t.opts.orderedOutput {
+		window := t.opts.orderedOutputWindow
+		if window < 1 {
+			window = t.opts.concurrency
+		}
+
+		slotsCh = make(chan *orderedSlot, window)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return t.dispatch(ctx, jobsChs, slotsCh)
+	})
+	for i := 0; i < t.opts.concurrency; i++ {
+		jobsCh := jobsChs[i%len(jobsChs)]
+		g.Go(func() error {
+			return t.runJobsWorker(ctx, jobsCh)
+		})
+	}
+	if slotsCh != nil {
+		g.Go(func() error {
+			return t.emitOrdered(ctx, slotsCh)
+		})
+	}
+
+	return g.Wait()
+}
+
+func (t *transformerDemux) dispatch(ctx context.Context, jobsChs []chan transformerJob, slotsCh chan<- *orderedSlot) error {
+	defer func() {
+		for _, jobsCh := range jobsChs {
+			close(jobsCh)
+		}
+
+		if slotsCh != nil {
+			close(slotsCh)
+		}
+	}()
+
+	var (
+		inMsg  Message
+		job    transformerJob
+		jobsCh chan transformerJob
+		ok     bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case inMsg, ok = <-t.inputCh:
+			if !ok {
+				return nil
+			}
+		}
+
+		job = transformerJob{msg: inMsg}
+
+		if slotsCh != nil {
+			job.slot = newOrderedSlot()
+
+			// blocks if reorder window is full
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case slotsCh <- job.slot:
+			}
+		}
+
+		jobsCh = jobsChs[0]
+		if t.opts.partitionKey != nil {
+			jobsCh = jobsChs[partitionIndex(t.opts.partitionKey(inMsg), len(jobsChs))]
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case jobsCh <- job:
+		}
+	}
+}
+
+func (t *transformerDemux) runJobsWorker(ctx context.Context, jobsCh <-chan transformerJob) error {
+	var (
+		job     transformerJob
+		emitter senderEmitter
+		ok      bool
+		err     error
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case job, ok = <-jobsCh:
+			if !ok {
+				return nil
+			}
+
+			emitter = nil
+			if job.slot != nil {
+				emitter = job.slot.emit
+			}
+
+			err = t.process(ctx, job.msg, emitter)
+
+			if job.slot != nil {
+				job.slot.done()
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (t *transformerDemux) emitOrdered(ctx context.Context, slotsCh <-chan *orderedSlot) error {
+	for slot := range slotsCh {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-slot.doneCh:
+		}
+
+		for _, output := range slot.outputs {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case t.outputChs[output.outChNr] <- output.msg:
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package etl
+
+import (
+	"context"
+)
+
+// FilterFunc decides whether message should be passed further
+type FilterFunc func(ctx context.Context, msg Message) (bool, error)
+
+// MapFunc returns payload of an output message
+type MapFunc func(ctx context.Context, msg Message) (interface{}, error)
+
+// FlatMapFunc returns payloads of output messages, message is dropped when no payloads are returned
+type FlatMapFunc func(ctx context.Context, msg Message) ([]interface{}, error)
+
+// Filter creates a transformer passing further messages accepted by the function. Rejected messages are acknowledged.
+func Filter(inputCh <-chan Message, fn FilterFunc, optsSetters ...TransformerOption) Transformer {
+	return NewTransformer(inputCh, func(ctx context.Context, inMsg Message, sender Sender) error {
+		ok, err := fn(ctx, inMsg)
+		if err != nil || !ok {
+			return err
+		}
+
+		return sender.SendMessage(ctx, inMsg)
+	}, optsSetters...)
+}
+
+// Map creates a transformer replacing payload of every message with the one returned by the function
+func Map(inputCh <-chan Message, fn MapFunc, optsSetters ...TransformerOption) Transformer {
+	return NewTransformer(inputCh, func(ctx context.Context, inMsg Message, sender Sender) error {
+		payload, err := fn(ctx, inMsg)
+		if err != nil {
+			return err
+		}
+
+		return sender.Send(ctx, payload)
+	}, optsSetters...)
+}
+
+// FlatMap creates a transformer sending a message for every payload returned by the function
+func FlatMap(inputCh <-chan Message, fn FlatMapFunc, optsSetters ...TransformerOption) Transformer {
+	return NewTransformer(inputCh, func(ctx context.Context, inMsg Message, sender Sender) error {
+		payloads, err := fn(ctx, inMsg)
+		if err != nil {
+			return err
+		}
+
+		for _, payload := range payloads {
+			err = sender.Send(ctx, payload)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, optsSetters...)
+}
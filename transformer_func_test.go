@@ -0,0 +1,85 @@
+package etl_test
+
+import (
+	"context"
+	"errors"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hook := &fakeAckHook{}
+	extractor := etl.NewExtractor(newFakeExtractor(1, 2, 3, 4),
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	filter := etl.Filter(extractor.OutputCh(), func(ctx context.Context, msg etl.Message) (bool, error) {
+		return isEven(msg), nil
+	})
+	l := &fakeLoader{}
+	loader := etl.NewLoader(filter.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor, filter, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{2, 4}, payloadsOf(l))
+	require.ElementsMatch(t, []interface{}{1, 2, 3, 4}, hook.acked)
+}
+
+func TestMap(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := etl.Map(newFilledChannel(1, 2, 3), func(ctx context.Context, msg etl.Message) (interface{}, error) {
+		return msg.Payload().(int) * 10, nil
+	})
+	l := &fakeLoader{}
+	loader := etl.NewLoader(m.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, m, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{10, 20, 30}, payloadsOf(l))
+}
+
+func TestMap_FailOnError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errTest := errors.New("test")
+	m := etl.Map(newFilledChannel(1, 2, 3), func(ctx context.Context, msg etl.Message) (interface{}, error) {
+		if msg.Payload() == 2 {
+			return nil, errTest
+		}
+
+		return msg.Payload(), nil
+	}, etl.TransformerWithFailOnError(false))
+	l := &fakeLoader{}
+	loader := etl.NewLoader(m.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, m, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{1, 3}, payloadsOf(l))
+}
+
+func TestFlatMap(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := etl.FlatMap(newFilledChannel(1, 2, 3), func(ctx context.Context, msg etl.Message) ([]interface{}, error) {
+		var payloads []interface{}
+		for i := 0; i < msg.Payload().(int)-1; i++ {
+			payloads = append(payloads, msg.Payload())
+		}
+
+		return payloads, nil
+	})
+	l := &fakeLoader{}
+	loader := etl.NewLoader(m.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, m, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{2, 3, 3}, payloadsOf(l))
+}
@@ -0,0 +1,29 @@
+package etl
+
+import (
+	"context"
+)
+
+type transformerMux struct {
+	m Mux
+	t Transformer
+}
+
+// NewTransformerMux creates a transformer consuming messages from multiple input channels. Output channel is closed
+// once all input channels are closed.
+func NewTransformerMux(inputChs []<-chan Message, handler TransformerHandler, optsSetters ...TransformerOption) Transformer {
+	m := NewMux(inputChs)
+
+	return &transformerMux{
+		m: m,
+		t: NewTransformer(m.OutputCh(), handler, optsSetters...),
+	}
+}
+
+func (t *transformerMux) OutputCh() <-chan Message {
+	return t.t.OutputCh()
+}
+
+func (t *transformerMux) Run(ctx context.Context) error {
+	return RunAll(ctx, t.m, t.t)
+}
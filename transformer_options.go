@@ -1,15 +1,28 @@
 package etl
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type transformerOptions struct {
 	hooksPreRun     []TransformerPreRunHook
 	hooksOnError    []TransformerOnErrorHook
 	hooksOnComplete []TransformerOnComplete
+	middlewares     []TransformerMiddleware
 
+	retryPolicy    *RetryPolicy
+	deadLetterSink DeadLetterSink
+	handlerTimeout time.Duration
+
+	name                    string
 	outputChannelBufferSize int
 	concurrency             int
 	failOnErr               bool
+
+	orderedOutput       bool
+	orderedOutputWindow int
+	partitionKey        PartitionKeyFunc
 }
 
 func newTransformerOptions(optsSetters ...TransformerOption) *transformerOptions {
@@ -29,6 +42,11 @@ func newTransformerOptions(optsSetters ...TransformerOption) *transformerOptions
 
 type TransformerOption func(o *transformerOptions)
 
+// TransformerWithName sets name of the stage, used to describe it e.g. in dead letters
+func TransformerWithName(name string) TransformerOption {
+	return func(o *transformerOptions) { o.name = name }
+}
+
 type TransformerPreRunHook func(ctx context.Context, inputCh <-chan Message) error
 
 func TransformerWithPreRunHook(hook TransformerPreRunHook) TransformerOption {
@@ -47,14 +65,63 @@ func TransformerWithOnCompleteHook(hook TransformerOnComplete) TransformerOption
 	return func(o *transformerOptions) { o.hooksOnComplete = append(o.hooksOnComplete, hook) }
 }
 
+// TransformerWithMiddleware wraps handler with a middleware. The first middleware is the outermost one.
+func TransformerWithMiddleware(middleware TransformerMiddleware) TransformerOption {
+	return func(o *transformerOptions) { o.middlewares = append(o.middlewares, middleware) }
+}
+
+// TransformerWithOptions applies several options at once, e.g. instrumentation consisting of a middleware and hooks
+func TransformerWithOptions(optsSetters ...TransformerOption) TransformerOption {
+	return func(o *transformerOptions) {
+		for _, setter := range optsSetters {
+			if setter != nil {
+				setter(o)
+			}
+		}
+	}
+}
+
 func TransformerWithOutputChannelBufferSize(size int) TransformerOption {
 	return func(o *transformerOptions) { o.outputChannelBufferSize = size }
 }
 
+// TransformerWithConcurrency sets number of workers. Values lower than 1 are treated as 1.
 func TransformerWithConcurrency(concurrency int) TransformerOption {
-	return func(o *transformerOptions) { o.concurrency = concurrency }
+	return func(o *transformerOptions) { o.concurrency = atLeastOne(concurrency) }
 }
 
 func TransformerWithFailOnError(failOnErr bool) TransformerOption {
 	return func(o *transformerOptions) { o.failOnErr = failOnErr }
 }
+
+// TransformerWithRetry retries failed handler calls according to the policy, before error hooks are called
+func TransformerWithRetry(policy RetryPolicy) TransformerOption {
+	return func(o *transformerOptions) { o.retryPolicy = &policy }
+}
+
+// TransformerWithDeadLetterSink routes messages that failed processing to the sink, after error hooks are called
+func TransformerWithDeadLetterSink(sink DeadLetterSink) TransformerOption {
+	return func(o *transformerOptions) { o.deadLetterSink = sink }
+}
+
+// TransformerWithHandlerTimeout limits duration of every handler call, by setting a deadline on its context. Handler
+// is expected to return once the context is done. Timed out calls are retried and reported as any other error
+func TransformerWithHandlerTimeout(timeout time.Duration) TransformerOption {
+	return func(o *transformerOptions) { o.handlerTimeout = timeout }
+}
+
+// TransformerWithOrderedOutput emits output messages in order of input messages, while still processing them
+// concurrently. At most window input messages are buffered while waiting for earlier ones to be processed. If window
+// is lower than 1, concurrency is used, which is at least 1.
+func TransformerWithOrderedOutput(window int) TransformerOption {
+	return func(o *transformerOptions) {
+		o.orderedOutput = true
+		o.orderedOutputWindow = window
+	}
+}
+
+// TransformerWithPartitionKey dispatches messages with the same key to the same worker, so they are processed
+// sequentially in order, while messages with different keys are processed concurrently
+func TransformerWithPartitionKey(keyFn PartitionKeyFunc) TransformerOption {
+	return func(o *transformerOptions) { o.partitionKey = keyFn }
+}
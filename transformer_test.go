@@ -0,0 +1,99 @@
+package etl_test
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestTransformer_OrderedOutput(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var payload []interface{}
+	for i := 0; i < 20; i++ {
+		payload = append(payload, i)
+	}
+
+	extractor := etl.NewExtractor(newFakeExtractor(payload...))
+	transformer := etl.NewTransformerDemux(extractor.OutputCh(), func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+		data := inMsg.Payload().(int)
+
+		// earlier messages take longer to process
+		time.Sleep(time.Duration(20-data) * time.Millisecond)
+
+		err := sender.SendCh(ctx, 0, data)
+		if err != nil {
+			return err
+		}
+
+		err = sender.SendCh(ctx, 0, -data)
+		if err != nil {
+			return err
+		}
+
+		return sender.SendCh(ctx, 1, data)
+	}, 2,
+		etl.TransformerWithConcurrency(5),
+		etl.TransformerWithOrderedOutput(10),
+	)
+	first, second := &fakeLoader{}, &fakeLoader{}
+	loader1 := etl.NewLoader(transformer.OutputCh(0), first.Handle)
+	loader2 := etl.NewLoader(transformer.OutputCh(1), second.Handle)
+
+	err := etl.RunAll(ctx, extractor, transformer, loader1, loader2)
+	require.NoError(t, err)
+
+	require.Equal(t, 40, len(first.calls))
+	require.Equal(t, 20, len(second.calls))
+	for i := 0; i < 20; i++ {
+		require.Equal(t, i, first.calls[2*i].Payload())
+		require.Equal(t, -i, first.calls[2*i+1].Payload())
+		require.Equal(t, i, second.calls[i].Payload())
+	}
+}
+
+func TestTransformer_OrderedOutputWithZeroConcurrency(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	extractor := etl.NewExtractor(newFakeExtractor(1, 2, 3))
+	transformer := etl.NewTransformer(extractor.OutputCh(), fakeTransformer,
+		etl.TransformerWithConcurrency(0),
+		etl.TransformerWithOrderedOutput(0),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(transformer.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor, transformer, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{2, 4, 6}, payloadsOf(l))
+}
+
+func TestTransformer_OrderedOutputSkipsFailedMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	extractor := etl.NewExtractor(newFakeExtractor(1, 2, 3))
+	transformer := etl.NewTransformer(extractor.OutputCh(), func(ctx context.Context, inMsg etl.Message, sender etl.Sender) error {
+		if inMsg.Payload() == 2 {
+			return etl.ErrMessageNacked
+		}
+
+		return sender.SendMessage(ctx, inMsg)
+	},
+		etl.TransformerWithConcurrency(3),
+		etl.TransformerWithOrderedOutput(0),
+		etl.TransformerWithFailOnError(false),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(transformer.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor, transformer, loader)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(l.calls))
+	require.Equal(t, 1, l.calls[0].Payload())
+	require.Equal(t, 3, l.calls[1].Payload())
+}
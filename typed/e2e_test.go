@@ -0,0 +1,88 @@
+package typed_test
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+	"github.com/damian-szulc/go-etl/typed"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func newFakeExtractor[T any](payload ...T) typed.ExtractorHandler[T] {
+	return func(ctx context.Context, sender typed.Sender[T]) error {
+		for _, p := range payload {
+			err := sender.Send(ctx, p)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+func TestTyped_BasePipeline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var loaded []string
+
+	extractor := typed.NewExtractor(newFakeExtractor(1, 2))
+	transformer := typed.NewTransformer(extractor.Output(), func(ctx context.Context, inMsg typed.Message[int], sender typed.Sender[string]) error {
+		return sender.Send(ctx, strconv.Itoa(inMsg.Payload()*2))
+	})
+	loader := typed.NewLoader(transformer.Output(), func(ctx context.Context, msg typed.Message[string]) error {
+		loaded = append(loaded, msg.Payload())
+		return nil
+	})
+
+	err := etl.RunAll(ctx, extractor, transformer, loader)
+	require.NoError(t, err)
+	require.Equal(t, []string{"2", "4"}, loaded)
+}
+
+func TestTyped_BatchedLoader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls [][]int
+
+	extractor := typed.NewExtractor(newFakeExtractor(1, 2))
+	loader := typed.NewLoaderBatched(extractor.Output(), func(ctx context.Context, msgs []typed.Message[int]) error {
+		var batch []int
+		for _, msg := range msgs {
+			batch = append(batch, msg.Payload())
+		}
+
+		calls = append(calls, batch)
+		return nil
+	}, etl.LoaderBatchedWithFixedSizeBatches(2))
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.NoError(t, err)
+	require.Equal(t, [][]int{{1, 2}}, calls)
+}
+
+func TestTyped_FailsOnMismatchedUntypedStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	extractor := etl.NewExtractor(func(ctx context.Context, sender etl.Sender) error {
+		return sender.Send(ctx, "not an int")
+	})
+	loader := typed.NewLoader(typed.NewStream[int](extractor.OutputCh()), func(ctx context.Context, msg typed.Message[int]) error {
+		return nil
+	})
+
+	err := etl.RunAll(ctx, extractor, loader)
+	require.Equal(t, etl.ErrCastingFailed, err)
+}
+
+func TestTyped_MessageKeepsUntypedMetadata(t *testing.T) {
+	msg := typed.NewMessage("payload")
+
+	require.Equal(t, "payload", msg.Payload())
+	require.Equal(t, "payload", msg.Untyped().Payload())
+	require.Equal(t, msg.CreatedAt(), msg.Untyped().CreatedAt())
+}
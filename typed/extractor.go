@@ -0,0 +1,31 @@
+package typed
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+)
+
+type ExtractorHandler[T any] func(ctx context.Context, sender Sender[T]) error
+
+type Extractor[T any] interface {
+	etl.Runner
+	etl.Stopper
+	OutputCh() <-chan etl.Message
+	Output() Stream[T]
+}
+
+type extractor[T any] struct {
+	etl.Extractor
+}
+
+func NewExtractor[T any](handler ExtractorHandler[T], optsSetters ...etl.ExtractorOption) Extractor[T] {
+	return &extractor[T]{
+		Extractor: etl.NewExtractor(func(ctx context.Context, s etl.Sender) error {
+			return handler(ctx, newSender[T](s))
+		}, optsSetters...),
+	}
+}
+
+func (e *extractor[T]) Output() Stream[T] {
+	return NewStream[T](e.OutputCh())
+}
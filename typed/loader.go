@@ -0,0 +1,19 @@
+package typed
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+)
+
+type LoaderHandler[T any] func(ctx context.Context, message Message[T]) error
+
+func NewLoader[T any](input Stream[T], handler LoaderHandler[T], optsSetters ...etl.LoaderOption) etl.Loader {
+	return etl.NewLoader(input.Ch(), func(ctx context.Context, msg etl.Message) error {
+		typedMsg, err := FromMessage[T](msg)
+		if err != nil {
+			return err
+		}
+
+		return handler(ctx, typedMsg)
+	}, optsSetters...)
+}
@@ -0,0 +1,19 @@
+package typed
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+)
+
+type LoaderBatchedHandler[T any] func(ctx context.Context, messages []Message[T]) error
+
+func NewLoaderBatched[T any](input Stream[T], handler LoaderBatchedHandler[T], optsSetters ...etl.LoaderBatchedOption) etl.LoaderBatched {
+	return etl.NewLoaderBatched(input.Ch(), func(ctx context.Context, msgs []etl.Message) error {
+		typedMsgs, err := fromMessages[T](msgs)
+		if err != nil {
+			return err
+		}
+
+		return handler(ctx, typedMsgs)
+	}, optsSetters...)
+}
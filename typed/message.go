@@ -0,0 +1,80 @@
+package typed
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+	"time"
+)
+
+// Message is a type-safe view over etl.Message, carrying a payload of type T.
+type Message[T any] interface {
+	ID() string
+	Payload() T
+	Header(key string) string
+	Headers() map[string]string
+	CreatedAt() time.Time
+	ProcessingStartedAt() time.Time
+	EventTime() time.Time
+	Watermark() time.Time
+	Ack(ctx context.Context) error
+	Nack(ctx context.Context, err error) error
+	// Untyped returns the underlying etl.Message
+	Untyped() etl.Message
+}
+
+type message[T any] struct {
+	etl.Message
+	payload T
+}
+
+// NewMessage creates a new message carrying payload of type T.
+func NewMessage[T any](payload T, optsSetters ...etl.MessageOption) Message[T] {
+	return &message[T]{
+		Message: etl.NewMessage(payload, optsSetters...),
+		payload: payload,
+	}
+}
+
+// FromMessage casts an untyped message into a typed one. Returns etl.ErrCastingFailed if payload is not of type T.
+func FromMessage[T any](msg etl.Message) (Message[T], error) {
+	var payload T
+
+	if raw := msg.Payload(); raw != nil {
+		var ok bool
+		payload, ok = raw.(T)
+		if !ok {
+			return nil, etl.ErrCastingFailed
+		}
+	}
+
+	return &message[T]{
+		Message: msg,
+		payload: payload,
+	}, nil
+}
+
+func fromMessages[T any](msgs []etl.Message) ([]Message[T], error) {
+	var (
+		typedMsgs = make([]Message[T], 0, len(msgs))
+		typedMsg  Message[T]
+		err       error
+	)
+	for _, msg := range msgs {
+		typedMsg, err = FromMessage[T](msg)
+		if err != nil {
+			return nil, err
+		}
+
+		typedMsgs = append(typedMsgs, typedMsg)
+	}
+
+	return typedMsgs, nil
+}
+
+func (m *message[T]) Payload() T {
+	return m.payload
+}
+
+func (m *message[T]) Untyped() etl.Message {
+	return m.Message
+}
@@ -0,0 +1,29 @@
+package typed
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+)
+
+type Sender[T any] interface {
+	// Create message and sends it to the output channel
+	Send(ctx context.Context, payload T) error
+	// Send message to the output channel
+	SendMessage(ctx context.Context, message Message[T]) error
+}
+
+type sender[T any] struct {
+	s etl.Sender
+}
+
+func newSender[T any](s etl.Sender) Sender[T] {
+	return sender[T]{s: s}
+}
+
+func (s sender[T]) Send(ctx context.Context, payload T) error {
+	return s.s.Send(ctx, payload)
+}
+
+func (s sender[T]) SendMessage(ctx context.Context, msg Message[T]) error {
+	return s.s.SendMessage(ctx, msg.Untyped())
+}
@@ -0,0 +1,20 @@
+package typed
+
+import "github.com/damian-szulc/go-etl"
+
+// Stream is a channel of messages carrying payloads of type T. It allows compiler to check that stages are wired together
+// with matching types.
+type Stream[T any] struct {
+	ch <-chan etl.Message
+}
+
+// NewStream declares that messages flowing through ch carry payloads of type T. It can be used to connect an untyped
+// stage to a typed one.
+func NewStream[T any](ch <-chan etl.Message) Stream[T] {
+	return Stream[T]{ch: ch}
+}
+
+// Ch returns underlying channel, so stream can be consumed by untyped stages.
+func (s Stream[T]) Ch() <-chan etl.Message {
+	return s.ch
+}
@@ -0,0 +1,35 @@
+package typed
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+)
+
+type TransformerHandler[In, Out any] func(ctx context.Context, inMsg Message[In], sender Sender[Out]) error
+
+type Transformer[T any] interface {
+	etl.Runner
+	OutputCh() <-chan etl.Message
+	Output() Stream[T]
+}
+
+type transformer[T any] struct {
+	etl.Transformer
+}
+
+func NewTransformer[In, Out any](input Stream[In], handler TransformerHandler[In, Out], optsSetters ...etl.TransformerOption) Transformer[Out] {
+	return &transformer[Out]{
+		Transformer: etl.NewTransformer(input.Ch(), func(ctx context.Context, inMsg etl.Message, s etl.Sender) error {
+			typedMsg, err := FromMessage[In](inMsg)
+			if err != nil {
+				return err
+			}
+
+			return handler(ctx, typedMsg, newSender[Out](s))
+		}, optsSetters...),
+	}
+}
+
+func (t *transformer[T]) Output() Stream[T] {
+	return NewStream[T](t.OutputCh())
+}
@@ -0,0 +1,95 @@
+package etl
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type watermarkedMessage struct {
+	Message
+	watermark time.Time
+}
+
+func (m *watermarkedMessage) Watermark() time.Time {
+	return m.watermark
+}
+
+// withWatermark advances watermark of a message. Watermark is never moved back
+func withWatermark(msg Message, watermark time.Time) Message {
+	if !watermark.After(msg.Watermark()) {
+		return msg
+	}
+
+	return &watermarkedMessage{Message: msg, watermark: watermark}
+}
+
+// withExactWatermark sets watermark of a message, also when it's behind the current one
+func withExactWatermark(msg Message, watermark time.Time) Message {
+	if watermark.Equal(msg.Watermark()) {
+		return msg
+	}
+
+	return &watermarkedMessage{Message: msg, watermark: watermark}
+}
+
+func maxWatermark(msgs []Message) time.Time {
+	var watermark time.Time
+	for _, msg := range msgs {
+		if msg.Watermark().After(watermark) {
+			watermark = msg.Watermark()
+		}
+	}
+
+	return watermark
+}
+
+// watermarkGenerator stamps messages with a watermark trailing the latest event time by maxOutOfOrderness
+type watermarkGenerator struct {
+	sync.Mutex
+	maxOutOfOrderness time.Duration
+	maxEventTime      time.Time
+}
+
+func (g *watermarkGenerator) stamp(msg Message) Message {
+	g.Lock()
+	if msg.EventTime().After(g.maxEventTime) {
+		g.maxEventTime = msg.EventTime()
+	}
+	watermark := g.maxEventTime.Add(-g.maxOutOfOrderness)
+	g.Unlock()
+
+	return withWatermark(msg, watermark)
+}
+
+// LateDataPolicy decides what happens with a late message, i.e. one which event time is behind the watermark of a
+// stage. Returns true if message should be processed anyway.
+type LateDataPolicy func(ctx context.Context, msg Message) (bool, error)
+
+// LateDataAdmit processes late messages as any other message
+func LateDataAdmit() LateDataPolicy {
+	return func(ctx context.Context, msg Message) (bool, error) {
+		return true, nil
+	}
+}
+
+// LateDataDrop acknowledges and drops late messages
+func LateDataDrop() LateDataPolicy {
+	return func(ctx context.Context, msg Message) (bool, error) {
+		return false, msg.Ack(ctx)
+	}
+}
+
+// LateDataToChannel sends late messages to a side output channel. They're acknowledged once consumer of the channel
+// acknowledges them.
+func LateDataToChannel(ch chan<- Message) LateDataPolicy {
+	return func(ctx context.Context, msg Message) (bool, error) {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case ch <- msg:
+		}
+
+		return false, nil
+	}
+}
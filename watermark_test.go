@@ -0,0 +1,111 @@
+package etl_test
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// newEventTimeExtractor sends integers as messages with event time of that many seconds since epoch
+func newEventTimeExtractor(seconds ...int) etl.ExtractorHandler {
+	return func(ctx context.Context, sender etl.Sender) error {
+		for _, s := range seconds {
+			err := sender.SendMessage(ctx, etl.NewMessage(s, etl.MessageWithEventTime(time.Unix(int64(s), 0))))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+func TestMessage_EventTime(t *testing.T) {
+	msg := etl.NewMessage(1)
+	require.Equal(t, msg.CreatedAt(), msg.EventTime())
+	require.True(t, msg.Watermark().IsZero())
+
+	msg = etl.NewMessage(1, etl.MessageWithEventTime(time.Unix(10, 0)), etl.MessageWithWatermark(time.Unix(5, 0)))
+	require.Equal(t, time.Unix(10, 0), msg.EventTime())
+	require.Equal(t, time.Unix(5, 0), msg.Watermark())
+}
+
+func TestExtractor_Watermarks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	extractor := etl.NewExtractor(newEventTimeExtractor(10, 5, 20), etl.ExtractorWithWatermarks(3*time.Second))
+	transformer := etl.NewTransformer(extractor.OutputCh(), fakeTransformer)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(transformer.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor, transformer, loader)
+	require.NoError(t, err)
+
+	var eventTimes, watermarks []time.Time
+	for _, call := range l.calls {
+		eventTimes = append(eventTimes, call.EventTime())
+		watermarks = append(watermarks, call.Watermark())
+	}
+	require.Equal(t, []time.Time{time.Unix(10, 0), time.Unix(5, 0), time.Unix(20, 0)}, eventTimes)
+	require.Equal(t, []time.Time{time.Unix(7, 0), time.Unix(7, 0), time.Unix(17, 0)}, watermarks)
+}
+
+func TestWindowing_ClosesWindowsOnWatermark(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lateCh := make(chan etl.Message, 10)
+	extractor := etl.NewExtractor(newEventTimeExtractor(1, 2, 6, 3, 11), etl.ExtractorWithWatermarks(0))
+	windowing := etl.NewWindowing(extractor.OutputCh(), etl.TumblingWindow(5*time.Second), fakeCountAggregate,
+		etl.WindowingWithLateDataPolicy(etl.LateDataToChannel(lateCh)),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(windowing.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor, windowing, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{2, 1, 1}, payloadsOf(l))
+	require.Equal(t, time.Unix(5, 0), l.calls[0].EventTime())
+
+	close(lateCh)
+	var late []interface{}
+	for msg := range lateCh {
+		late = append(late, msg.Payload())
+	}
+	require.Equal(t, []interface{}{3}, late)
+}
+
+func TestWindowing_AdmitsLateData(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	extractor := etl.NewExtractor(newEventTimeExtractor(1, 2, 6, 3, 11), etl.ExtractorWithWatermarks(0))
+	windowing := etl.NewWindowing(extractor.OutputCh(), etl.TumblingWindow(5*time.Second), fakeCountAggregate,
+		etl.WindowingWithLateDataPolicy(etl.LateDataAdmit()),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(windowing.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor, windowing, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{2, 1, 1, 1}, payloadsOf(l))
+}
+
+func TestWindowing_AllowedLatenessKeepsWindowsOpen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	extractor := etl.NewExtractor(newEventTimeExtractor(1, 2, 6, 3, 11), etl.ExtractorWithWatermarks(0))
+	windowing := etl.NewWindowing(extractor.OutputCh(), etl.TumblingWindow(5*time.Second), fakeCountAggregate,
+		etl.WindowingWithAllowedLateness(2*time.Second),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(windowing.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor, windowing, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{3, 1, 1}, payloadsOf(l))
+}
@@ -0,0 +1,443 @@
+package etl
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"sort"
+	"time"
+)
+
+// Window is a group of messages with the same key, which timestamps fall in [Start, End)
+type Window struct {
+	Key      string
+	Start    time.Time
+	End      time.Time
+	Messages []Message
+}
+
+// WindowType decides how messages are grouped into windows
+type WindowType struct {
+	size  time.Duration
+	slide time.Duration
+	gap   time.Duration
+}
+
+// TumblingWindow groups messages into consecutive, non-overlapping windows of the given size
+func TumblingWindow(size time.Duration) WindowType {
+	return WindowType{size: size, slide: size}
+}
+
+// SlidingWindow groups messages into windows of the given size, starting every slide. Message belongs to every
+// window covering its timestamp. If slide is not positive, windows are tumbling
+func SlidingWindow(size, slide time.Duration) WindowType {
+	if slide <= 0 {
+		slide = size
+	}
+
+	return WindowType{size: size, slide: slide}
+}
+
+// SessionWindow groups messages into windows, which are closed after a gap of inactivity
+func SessionWindow(gap time.Duration) WindowType {
+	return WindowType{gap: gap}
+}
+
+// validate reports window types, which can't cover any timestamp
+func (w WindowType) validate() error {
+	if w.gap > 0 {
+		return nil
+	}
+
+	if w.size <= 0 || w.slide <= 0 {
+		return ErrWindowSizeNotPositive
+	}
+
+	return nil
+}
+
+// end returns end of the latest window covering the timestamp
+func (w WindowType) end(t time.Time) time.Time {
+	if w.gap > 0 {
+		return t.Add(w.gap)
+	}
+
+	return t.Truncate(w.slide).Add(w.size)
+}
+
+// starts returns starts of all fixed windows covering the timestamp
+func (w WindowType) starts(t time.Time) []time.Time {
+	var starts []time.Time
+	for start := t.Truncate(w.slide); start.Add(w.size).After(t); start = start.Add(-w.slide) {
+		starts = append(starts, start)
+	}
+
+	return starts
+}
+
+// WindowHandler aggregates messages of a closed window. Returned aggregate is sent downstream, unless it's nil
+type WindowHandler func(ctx context.Context, window Window) (interface{}, error)
+
+// Windowing groups messages into windows and emits their aggregates. Windows are closed once the watermark passes
+// their end, or when input channel is closed. Until messages carry watermarks, wall clock is used instead. Messages of
+// a window are acknowledged once its aggregate is acknowledged.
+type Windowing interface {
+	Runner
+	OutputCh() <-chan Message
+}
+
+type windowing struct {
+	handler    WindowHandler
+	windowType WindowType
+
+	inputCh   <-chan Message
+	outputCh  chan Message
+	windows   map[string][]*Window
+	watermark time.Time
+
+	opts *windowingOptions
+}
+
+func NewWindowing(inputCh <-chan Message, windowType WindowType, handler WindowHandler, optsSetters ...WindowingOption) Windowing {
+	opts := newWindowingOptions(optsSetters...)
+
+	return &windowing{
+		handler:    handler,
+		windowType: windowType,
+
+		inputCh:  inputCh,
+		outputCh: make(chan Message, opts.outputChannelBufferSize),
+		windows:  make(map[string][]*Window),
+
+		opts: opts,
+	}
+}
+
+func (w *windowing) OutputCh() <-chan Message {
+	return w.outputCh
+}
+
+func (w *windowing) preRunHooks(ctx context.Context) error {
+	var err error
+	for _, hook := range w.opts.hooksPreRun {
+		err = hook(ctx, w.inputCh)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *windowing) onErrorHook(ctx context.Context, window Window, opErr error) error {
+	var err error
+	for _, hook := range w.opts.hooksOnError {
+		err = hook(ctx, window, opErr)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run groups messages into windows, until input channel is closed. Note that execution of this function is blocking, until processing is finished.
+func (w *windowing) Run(ctx context.Context) error {
+	err := w.windowType.validate()
+	if err != nil {
+		return err
+	}
+
+	err = w.preRunHooks(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to run windowing preRunHooks")
+	}
+
+	defer close(w.outputCh)
+
+	ticker := time.NewTicker(w.opts.tick)
+	defer ticker.Stop()
+
+	var (
+		inMsg Message
+		ok    bool
+		now   time.Time
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case inMsg, ok = <-w.inputCh:
+			if !ok {
+				return w.closeWindows(ctx, func(*Window) bool { return true })
+			}
+
+			err = w.receive(ctx, inMsg)
+			if err != nil {
+				return err
+			}
+		case now = <-ticker.C:
+			if !w.watermark.IsZero() {
+				continue
+			}
+
+			err = w.closeWindows(ctx, w.closedBefore(now))
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// expired reports whether window ending at the given time should be closed at time t
+func (w *windowing) expired(end, t time.Time) bool {
+	return !t.Before(end.Add(w.opts.allowedLateness))
+}
+
+// closedBefore returns a filter of windows, which should be closed at time t
+func (w *windowing) closedBefore(t time.Time) func(window *Window) bool {
+	return func(window *Window) bool {
+		return w.expired(window.End, t)
+	}
+}
+
+// receive handles late messages according to policy, adds message to windows and closes windows passed by watermark
+func (w *windowing) receive(ctx context.Context, inMsg Message) error {
+	var (
+		admit = true
+		err   error
+	)
+	if !w.watermark.IsZero() && w.expired(w.windowType.end(w.opts.timestamp(inMsg)), w.watermark) {
+		admit, err = w.opts.lateDataPolicy(ctx, inMsg)
+		if err != nil {
+			return errors.Wrap(err, "failed to handle late message")
+		}
+	}
+
+	if admit {
+		err = w.add(ctx, inMsg)
+		if err != nil {
+			return err
+		}
+	}
+
+	if inMsg.Watermark().After(w.watermark) {
+		w.watermark = inMsg.Watermark()
+	}
+
+	if w.watermark.IsZero() {
+		return nil
+	}
+
+	return w.closeWindows(ctx, w.closedBefore(w.watermark))
+}
+
+// add assigns message to windows. Message is acknowledged once all windows it belongs to are acknowledged.
+func (w *windowing) add(ctx context.Context, inMsg Message) error {
+	tracker := newAckTracker(func(ctx context.Context, err error) error {
+		if err != nil {
+			return inMsg.Nack(ctx, err)
+		}
+
+		return inMsg.Ack(ctx)
+	})
+	tracker.add()
+
+	var (
+		key = w.opts.key(inMsg)
+		t   = w.opts.timestamp(inMsg)
+	)
+	if w.windowType.gap > 0 {
+		w.addToSession(key, t, withAckTracker(inMsg, tracker))
+	} else {
+		for _, start := range w.windowType.starts(t) {
+			window := w.window(key, start)
+			window.Messages = append(window.Messages, withAckTracker(inMsg, tracker))
+		}
+	}
+
+	// message not assigned to any window is acknowledged right away
+	err := tracker.release(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to ack message not assigned to any window")
+	}
+
+	return nil
+}
+
+// window returns an open fixed window, creating it if necessary
+func (w *windowing) window(key string, start time.Time) *Window {
+	for _, window := range w.windows[key] {
+		if window.Start.Equal(start) {
+			return window
+		}
+	}
+
+	window := &Window{Key: key, Start: start, End: start.Add(w.windowType.size)}
+	w.windows[key] = append(w.windows[key], window)
+
+	return window
+}
+
+// addToSession adds message to a session, merging all sessions it connects
+func (w *windowing) addToSession(key string, t time.Time, msg Message) {
+	var (
+		session  = &Window{Key: key, Start: t, End: t.Add(w.windowType.gap), Messages: []Message{msg}}
+		sessions = w.windows[key]
+		rest     []*Window
+	)
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Start.Before(sessions[j].Start) })
+	for _, s := range sessions {
+		if s.Start.After(session.End) || session.Start.After(s.End) {
+			rest = append(rest, s)
+			continue
+		}
+
+		if s.Start.Before(session.Start) {
+			session.Start = s.Start
+		}
+		if s.End.After(session.End) {
+			session.End = s.End
+		}
+		session.Messages = append(s.Messages, session.Messages...)
+	}
+
+	w.windows[key] = append(rest, session)
+}
+
+// closeWindows processes and removes windows matching the filter, in order of their end
+func (w *windowing) closeWindows(ctx context.Context, filter func(window *Window) bool) error {
+	var closed []*Window
+	for key, windows := range w.windows {
+		var open []*Window
+		for _, window := range windows {
+			if filter(window) {
+				closed = append(closed, window)
+			} else {
+				open = append(open, window)
+			}
+		}
+
+		if len(open) == 0 {
+			delete(w.windows, key)
+		} else {
+			w.windows[key] = open
+		}
+	}
+
+	sort.Slice(closed, func(i, j int) bool {
+		if !closed[i].End.Equal(closed[j].End) {
+			return closed[i].End.Before(closed[j].End)
+		}
+
+		return closed[i].Key < closed[j].Key
+	})
+
+	var err error
+	for _, window := range closed {
+		err = w.process(ctx, *window)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *windowing) newAckTracker(window Window) *ackTracker {
+	tracker := newAckTracker(func(ctx context.Context, err error) error {
+		if err != nil {
+			return nackMessages(ctx, window.Messages, err)
+		}
+
+		return ackMessages(ctx, window.Messages)
+	})
+	tracker.add()
+
+	return tracker
+}
+
+func (w *windowing) newWindowSender(window Window, tracker *ackTracker) Sender {
+	watermark := w.watermark
+
+	return newSender(
+		[]chan Message{w.outputCh},
+		[]MessageOption{
+			MessageWithProcessingStartedAt(window.Messages[0].ProcessingStartedAt()),
+			MessageWithEventTime(window.End),
+		},
+		func(ctx context.Context, outMsg Message, _ uint) error {
+			var err error
+			for _, hook := range w.opts.hooksOnComplete {
+				err = hook(ctx, window, outMsg)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		func(msg Message) Message {
+			return withAckTracker(withWatermark(msg, watermark), tracker)
+		},
+		nil,
+	)
+}
+
+// process runs handler for a closed window. Returns an error only if processing should be stopped.
+func (w *windowing) process(ctx context.Context, window Window) error {
+	var (
+		tracker = w.newAckTracker(window)
+		sender  = w.newWindowSender(window, tracker)
+
+		aggregate interface{}
+		hookCtx   context.Context
+		opErr     error
+		err       error
+
+		deadLettered bool
+	)
+
+	hookCtx, opErr = w.opts.retryPolicy.run(ctx, func(ctx context.Context) error {
+		ctx, cancel := contextWithHandlerTimeout(ctx, w.opts.handlerTimeout)
+		defer cancel()
+
+		return callRecovering(func() error {
+			aggregate, err = w.handler(ctx, window)
+			return err
+		}, window.Messages...)
+	})
+	if opErr == nil && aggregate != nil {
+		opErr = sender.Send(ctx, aggregate)
+	}
+	if opErr != nil {
+		err = w.onErrorHook(hookCtx, window, opErr)
+		if err != nil {
+			return errors.Wrap(err, "running windowing on error hook has failed")
+		}
+
+		deadLettered, err = sendToDeadLetterSink(hookCtx, w.opts.deadLetterSink, w.opts.name, window.Messages, opErr)
+		if err != nil {
+			return errors.Wrap(err, "failed to send window messages to dead letter sink")
+		}
+
+		if !deadLettered {
+			err = tracker.release(ctx, opErr)
+			if err != nil {
+				return errors.Wrap(err, "failed to nack window messages")
+			}
+		}
+
+		if w.opts.failOnErr {
+			return opErr
+		}
+
+		return nil
+	}
+
+	err = tracker.release(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to ack window messages")
+	}
+
+	return nil
+}
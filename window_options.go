@@ -0,0 +1,130 @@
+package etl
+
+import (
+	"context"
+	"time"
+)
+
+type windowingOptions struct {
+	hooksPreRun     []WindowingPreRunHook
+	hooksOnError    []WindowingOnErrorHook
+	hooksOnComplete []WindowingOnComplete
+
+	retryPolicy    *RetryPolicy
+	deadLetterSink DeadLetterSink
+	handlerTimeout time.Duration
+
+	name                    string
+	outputChannelBufferSize int
+	failOnErr               bool
+
+	key             PartitionKeyFunc
+	timestamp       WindowTimestampFunc
+	allowedLateness time.Duration
+	tick            time.Duration
+	lateDataPolicy  LateDataPolicy
+}
+
+func newWindowingOptions(optsSetters ...WindowingOption) *windowingOptions {
+	opts := &windowingOptions{
+		failOnErr: true,
+		key:       func(Message) string { return "" },
+		timestamp: func(msg Message) time.Time { return msg.EventTime() },
+		tick:      time.Second,
+
+		lateDataPolicy: LateDataDrop(),
+	}
+
+	for _, setter := range optsSetters {
+		if setter != nil {
+			setter(opts)
+		}
+	}
+
+	return opts
+}
+
+type WindowingOption func(o *windowingOptions)
+
+// WindowTimestampFunc returns time of a message used to assign it to windows
+type WindowTimestampFunc func(msg Message) time.Time
+
+// WindowingWithName sets name of the stage, used to describe it e.g. in dead letters
+func WindowingWithName(name string) WindowingOption {
+	return func(o *windowingOptions) { o.name = name }
+}
+
+type WindowingPreRunHook func(ctx context.Context, inputCh <-chan Message) error
+
+func WindowingWithPreRunHook(hook WindowingPreRunHook) WindowingOption {
+	return func(o *windowingOptions) { o.hooksPreRun = append(o.hooksPreRun, hook) }
+}
+
+type WindowingOnErrorHook func(ctx context.Context, window Window, err error) error
+
+func WindowingWithOnErrorHook(hook WindowingOnErrorHook) WindowingOption {
+	return func(o *windowingOptions) { o.hooksOnError = append(o.hooksOnError, hook) }
+}
+
+type WindowingOnComplete func(ctx context.Context, window Window, outMsg Message) error
+
+func WindowingWithOnCompleteHook(hook WindowingOnComplete) WindowingOption {
+	return func(o *windowingOptions) { o.hooksOnComplete = append(o.hooksOnComplete, hook) }
+}
+
+func WindowingWithOutputChannelBufferSize(size int) WindowingOption {
+	return func(o *windowingOptions) { o.outputChannelBufferSize = size }
+}
+
+func WindowingWithFailOnError(failOnErr bool) WindowingOption {
+	return func(o *windowingOptions) { o.failOnErr = failOnErr }
+}
+
+// WindowingWithRetry retries failed handler calls according to the policy, before error hooks are called
+func WindowingWithRetry(policy RetryPolicy) WindowingOption {
+	return func(o *windowingOptions) { o.retryPolicy = &policy }
+}
+
+// WindowingWithDeadLetterSink routes messages of windows that failed processing to the sink, after error hooks are
+// called. Every message of a failed window is reported separately
+func WindowingWithDeadLetterSink(sink DeadLetterSink) WindowingOption {
+	return func(o *windowingOptions) { o.deadLetterSink = sink }
+}
+
+// WindowingWithHandlerTimeout limits duration of every handler call, by setting a deadline on its context. Handler
+// is expected to return once the context is done. Timed out calls are retried and reported as any other error
+func WindowingWithHandlerTimeout(timeout time.Duration) WindowingOption {
+	return func(o *windowingOptions) { o.handlerTimeout = timeout }
+}
+
+// WindowingWithKey groups messages into separate windows by key, e.g. per user. By default all messages share windows
+func WindowingWithKey(keyFn PartitionKeyFunc) WindowingOption {
+	return func(o *windowingOptions) { o.key = keyFn }
+}
+
+// WindowingWithTimestamp sets time of a message used to assign it to windows. By default, event time of message is
+// used
+func WindowingWithTimestamp(timestampFn WindowTimestampFunc) WindowingOption {
+	return func(o *windowingOptions) { o.timestamp = timestampFn }
+}
+
+// WindowingWithAllowedLateness keeps windows open for a duration after their end, to include delayed messages
+func WindowingWithAllowedLateness(lateness time.Duration) WindowingOption {
+	return func(o *windowingOptions) { o.allowedLateness = lateness }
+}
+
+// WindowingWithTick sets how often windows are checked for being closed. Defaults to a second, which is also kept
+// when the tick isn't positive.
+func WindowingWithTick(tick time.Duration) WindowingOption {
+	return func(o *windowingOptions) {
+		if tick > 0 {
+			o.tick = tick
+		}
+	}
+}
+
+// WindowingWithLateDataPolicy sets what happens with messages, which windows have already been closed by the
+// watermark. By default, they're dropped
+func WindowingWithLateDataPolicy(policy LateDataPolicy) WindowingOption {
+	return func(o *windowingOptions) { o.lateDataPolicy = policy }
+}
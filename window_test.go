@@ -0,0 +1,140 @@
+package etl_test
+
+import (
+	"context"
+	"github.com/damian-szulc/go-etl"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type fakeEvent struct {
+	user string
+	at   int
+}
+
+func fakeEventTimestamp(msg etl.Message) time.Time {
+	return time.Unix(int64(msg.Payload().(fakeEvent).at), 0)
+}
+
+func fakeEventUser(msg etl.Message) string {
+	return msg.Payload().(fakeEvent).user
+}
+
+func fakeCountAggregate(ctx context.Context, window etl.Window) (interface{}, error) {
+	return len(window.Messages), nil
+}
+
+func TestWindowing_Tumbling(t *testing.T) {
+	windowing := etl.NewWindowing(
+		newFilledChannel(fakeEvent{at: 0}, fakeEvent{at: 1}, fakeEvent{at: 4}, fakeEvent{at: 5}, fakeEvent{at: 9}),
+		etl.TumblingWindow(5*time.Second),
+		fakeCountAggregate,
+		etl.WindowingWithTimestamp(fakeEventTimestamp),
+	)
+
+	require.Equal(t, []interface{}{3, 2}, runIntoLoader(t, windowing, windowing.OutputCh()))
+}
+
+func TestWindowing_IgnoresNonPositiveTick(t *testing.T) {
+	windowing := etl.NewWindowing(
+		newFilledChannel(fakeEvent{at: 0}, fakeEvent{at: 1}, fakeEvent{at: 4}, fakeEvent{at: 5}, fakeEvent{at: 9}),
+		etl.TumblingWindow(5*time.Second),
+		fakeCountAggregate,
+		etl.WindowingWithTimestamp(fakeEventTimestamp),
+		etl.WindowingWithTick(0),
+	)
+
+	require.Equal(t, []interface{}{3, 2}, runIntoLoader(t, windowing, windowing.OutputCh()))
+}
+
+func TestWindowing_RejectsNonPositiveSize(t *testing.T) {
+	for name, windowType := range map[string]etl.WindowType{
+		"tumbling": etl.TumblingWindow(0),
+		"sliding":  etl.SlidingWindow(-time.Second, time.Second),
+		"session":  etl.SessionWindow(0),
+	} {
+		t.Run(name, func(t *testing.T) {
+			windowing := etl.NewWindowing(newFilledChannel(fakeEvent{at: 0}), windowType, fakeCountAggregate)
+
+			err := windowing.Run(context.Background())
+			require.Equal(t, etl.ErrWindowSizeNotPositive, err)
+		})
+	}
+}
+
+func TestWindowing_Sliding(t *testing.T) {
+	windowing := etl.NewWindowing(
+		newFilledChannel(fakeEvent{at: 1}, fakeEvent{at: 6}, fakeEvent{at: 11}),
+		etl.SlidingWindow(10*time.Second, 5*time.Second),
+		fakeCountAggregate,
+		etl.WindowingWithTimestamp(fakeEventTimestamp),
+	)
+
+	require.Equal(t, []interface{}{1, 2, 2, 1}, runIntoLoader(t, windowing, windowing.OutputCh()))
+}
+
+func TestWindowing_SessionPerKey(t *testing.T) {
+	windowing := etl.NewWindowing(
+		newFilledChannel(
+			fakeEvent{user: "a", at: 0},
+			fakeEvent{user: "b", at: 1},
+			fakeEvent{user: "a", at: 4},
+			fakeEvent{user: "a", at: 2},
+			fakeEvent{user: "a", at: 10},
+		),
+		etl.SessionWindow(3*time.Second),
+		func(ctx context.Context, window etl.Window) (interface{}, error) {
+			return window.Key + ":" + window.End.Sub(window.Start).String(), nil
+		},
+		etl.WindowingWithTimestamp(fakeEventTimestamp),
+		etl.WindowingWithKey(fakeEventUser),
+	)
+
+	require.Equal(t, []interface{}{"b:3s", "a:7s", "a:3s"}, runIntoLoader(t, windowing, windowing.OutputCh()))
+}
+
+func TestWindowing_ClosesWindowsOnTimer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	inputCh := make(chan etl.Message, 2)
+	inputCh <- etl.NewMessage(1)
+	inputCh <- etl.NewMessage(2)
+	defer close(inputCh)
+
+	windowing := etl.NewWindowing(inputCh, etl.TumblingWindow(20*time.Millisecond), fakeCountAggregate,
+		etl.WindowingWithTick(5*time.Millisecond),
+	)
+	go func() {
+		_ = windowing.Run(ctx)
+	}()
+
+	select {
+	case msg := <-windowing.OutputCh():
+		require.Greater(t, msg.Payload(), 0)
+	case <-time.After(time.Second):
+		require.Fail(t, "window has not been closed")
+	}
+}
+
+func TestWindowing_AcksMessagesOnceAggregateIsAcked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hook := &fakeAckHook{}
+	extractor := etl.NewExtractor(newFakeExtractor(fakeEvent{at: 1}, fakeEvent{at: 11}),
+		etl.ExtractorWithOnAckHook(hook.OnAck),
+		etl.ExtractorWithOnNackHook(hook.OnNack),
+	)
+	windowing := etl.NewWindowing(extractor.OutputCh(), etl.SlidingWindow(10*time.Second, 5*time.Second), fakeCountAggregate,
+		etl.WindowingWithTimestamp(fakeEventTimestamp),
+	)
+	l := &fakeLoader{}
+	loader := etl.NewLoader(windowing.OutputCh(), l.Handle)
+
+	err := etl.RunAll(ctx, extractor, windowing, loader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{1, 1, 1, 1}, payloadsOf(l))
+	require.ElementsMatch(t, []interface{}{fakeEvent{at: 1}, fakeEvent{at: 11}}, hook.acked)
+}